@@ -0,0 +1,137 @@
+package game
+
+import (
+	"fmt"
+	"time"
+)
+
+// Auction is a timed sale of an unowned property to the highest bidder.
+type Auction struct {
+	Property   int       `json:"property"`
+	HighBid    int       `json:"highBid"`
+	HighBidder IdType    `json:"highBidder"`
+	Deadline   time.Time `json:"deadline"`
+}
+
+// GameBidBody is a bid in the running auction.
+type GameBidBody struct {
+	Amount int `json:"amount" validate:"required"`
+}
+
+// DeclinePurchase passes on the property the player landed on.
+// It goes up for auction if the rules say so, otherwise it stays with the bank.
+func (b *Board) DeclinePurchase(player *Player) (string, string, error) {
+	if b.PendingPurchase == nil {
+		return "", "", fmt.Errorf("nothing to decline")
+	}
+	msg := fmt.Sprintf("%s declined to buy %s", player.Name, b.Slots[*b.PendingPurchase].Name)
+	if !b.Rules.AuctionOnDecline {
+		b.PendingPurchase = nil
+	} else {
+		msg = joinMessages(msg, b.startAuction(*b.PendingPurchase))
+	}
+	b.settle(b.afterRollPhase())
+	return msg, "", nil
+}
+
+// StartAuction opens the bidding on a property and clears any pending purchase decision.
+func (b *Board) StartAuction(position int) string {
+	b.Lock()
+	defer b.Unlock()
+	return b.startAuction(position)
+}
+
+// startAuction is StartAuction for callers that already hold the board lock.
+func (b *Board) startAuction(position int) string {
+	b.PendingPurchase = nil
+	b.Auction = &Auction{
+		Property: position,
+		Deadline: time.Now().Add(b.Rules.AuctionDuration),
+	}
+	b.emit(EventAuctionStarted, nil, PropertyPayload{Property: position})
+	return fmt.Sprintf("Auction for %s started, bids of at least %d close after %s without a higher bid",
+		b.Slots[position].Name, b.Rules.MinBidIncrement, b.Rules.AuctionDuration)
+}
+
+// Bid raises the high bid in the running auction. Any solvent player who can cover the bid may take part.
+// It must be called with the board locked.
+func (b *Board) Bid(player *Player, body GameBidBody) (string, string, error) {
+	auction := b.Auction
+	if auction == nil {
+		return "", "", fmt.Errorf("no auction running")
+	}
+	if b.debtOf(player) != nil {
+		return "", "", fmt.Errorf("pay your debts before bidding")
+	}
+	if minimum := auction.HighBid + b.Rules.MinBidIncrement; body.Amount < minimum {
+		return "", "", fmt.Errorf("bid must be at least %d", minimum)
+	}
+	if player.Money < body.Amount {
+		return "", "", fmt.Errorf("insufficient funds")
+	}
+
+	auction.HighBid = body.Amount
+	auction.HighBidder = player.Id
+	// Every new bid restarts the countdown
+	auction.Deadline = time.Now().Add(b.Rules.AuctionDuration)
+	b.emit(EventBidPlaced, player, BidPayload{Property: auction.Property, Amount: body.Amount})
+	return fmt.Sprintf("%s bid %d for %s", player.Name, body.Amount, b.Slots[auction.Property].Name), "", nil
+}
+
+// startNextAuction opens the auction for the next queued property.
+// It must be called with the board locked.
+func (b *Board) startNextAuction() string {
+	position := b.AuctionQueue[0]
+	b.AuctionQueue = b.AuctionQueue[1:]
+	return b.startAuction(position)
+}
+
+// resolveAuction closes the running auction, selling the property to the high bidder.
+// It must be called with the board locked.
+func (b *Board) resolveAuction() string {
+	auction := b.Auction
+	b.Auction = nil
+	slot := &b.Slots[auction.Property]
+
+	if auction.HighBidder == nil {
+		b.emit(EventAuctionClosed, nil, PropertyPayload{Property: auction.Property})
+		return fmt.Sprintf("Auction for %s closed without bids", slot.Name)
+	}
+	winner := b.GetPlayer(auction.HighBidder)
+	if winner == nil {
+		b.emit(EventAuctionClosed, nil, PropertyPayload{Property: auction.Property})
+		return fmt.Sprintf("Auction for %s cancelled, the high bidder left the game", slot.Name)
+	}
+	position := auction.Property
+	err := b.transact(Transaction{From: winner, Money: auction.HighBid}, Transaction{To: winner, Properties: []IdType{&position}})
+	if err != nil {
+		b.emit(EventAuctionClosed, nil, PropertyPayload{Property: auction.Property})
+		return fmt.Sprintf("Auction for %s cancelled, %s can no longer pay %d", slot.Name, winner.Name, auction.HighBid)
+	}
+	b.emit(EventAuctionClosed, winner, PropertyPayload{Property: auction.Property, Price: auction.HighBid})
+	return fmt.Sprintf("%s won the auction for %s with %d", winner.Name, slot.Name, auction.HighBid)
+}
+
+// Tick advances timed game state, closing auctions whose countdown has run out
+// and trades nobody answered in time.
+// It returns the events to deliver, if anything happened.
+func (b *Board) Tick(now time.Time) []Event {
+	b.Lock()
+	defer b.Unlock()
+
+	msg := joinMessages(b.expireTrades(now), b.expireLoans(now))
+	if b.Auction != nil && !now.Before(b.Auction.Deadline) {
+		msg = joinMessages(msg, b.resolveAuction())
+		if len(b.AuctionQueue) > 0 {
+			msg = joinMessages(msg, b.startNextAuction())
+		}
+		b.refreshPhase()
+		msg = joinMessages(msg, b.invalidateTrades())
+	}
+	b.commitState()
+	events := b.drainEvents()
+	if msg != "" {
+		events = append(events, TextEvent(EventMessage, nil, msg))
+	}
+	return events
+}
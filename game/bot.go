@@ -0,0 +1,50 @@
+package game
+
+import "slices"
+
+// BotAction picks the next action for a player the game plays on their behalf,
+// such as one whose owner left. The bot plays it safe: it never buys or bids,
+// pays taxes the cheaper way, settles its debts when it can and ends its turn.
+// It returns false when there is nothing for the bot to do right now.
+func (b *Board) BotAction(player *Player) (string, interface{}, bool) {
+	b.Lock()
+	defer b.Unlock()
+
+	if b.GetPlayer(player.Id) == nil {
+		return "", nil, false
+	}
+	allowed := b.allowedActions(player)
+	if b.Phase == PhaseDebt {
+		// Only the debtor has anything to do, the others wait for them
+		if b.debtOf(player) == nil {
+			return "", nil, false
+		}
+		owed := 0
+		for _, debt := range b.Debts {
+			if *debt.Debtor == *player.Id {
+				owed += debt.Amount
+			}
+		}
+		if player.Money >= owed {
+			return "pay_debt", nil, true
+		}
+		return "bankrupt", nil, true
+	}
+
+	switch {
+	case slices.Contains(allowed, "pay_tax"):
+		slot := b.Slots[*b.PendingTax]
+		option := TaxModeFlat
+		if b.PendingPercentTax < slot.Price {
+			option = TaxModePercent
+		}
+		return "pay_tax", GameTaxBody{Option: option}, true
+	case slices.Contains(allowed, "decline"):
+		return "decline", nil, true
+	case slices.Contains(allowed, "go"):
+		return "go", nil, true
+	case slices.Contains(allowed, "end_turn"):
+		return "end_turn", nil, true
+	}
+	return "", nil, false
+}
@@ -0,0 +1,141 @@
+package game
+
+import "fmt"
+
+const (
+	// HotelState is the Slot.State of a property with a hotel, the last of the five rent tiers.
+	// States in between count the houses on the property.
+	HotelState = 4
+)
+
+// GamePropertyBody names the board slot a property action applies to.
+type GamePropertyBody struct {
+	Property IdType `json:"property" validate:"required"`
+}
+
+// slotAt returns the slot a client referred to, checking it is on the board.
+func (b *Board) slotAt(id IdType) (*Slot, error) {
+	if id == nil || *id < 0 || *id >= len(b.Slots) {
+		return nil, fmt.Errorf("invalid property")
+	}
+	return &b.Slots[*id], nil
+}
+
+// groupSlots returns the board positions of every slot in a color group.
+func (b *Board) groupSlots(group string) []int {
+	positions := []int{}
+	for i, slot := range b.Slots {
+		if slot.Group == group {
+			positions = append(positions, i)
+		}
+	}
+	return positions
+}
+
+// ownsGroup reports whether the player owns every property of a color group.
+func (b *Board) ownsGroup(player *Player, group string) bool {
+	if group == "" {
+		return false
+	}
+	for _, i := range b.groupSlots(group) {
+		if b.Slots[i].Owner != player.Id {
+			return false
+		}
+	}
+	return true
+}
+
+// BuildHouse adds a house to a property, or a hotel once it has the maximum houses.
+// The player must own the whole color group and build evenly across it.
+// It must be called with the board locked.
+func (b *Board) BuildHouse(player *Player, body GamePropertyBody) (string, string, error) {
+	slot, err := b.slotAt(body.Property)
+	if err != nil {
+		return "", "", err
+	}
+	if slot.Type != SlotTypeProperty || slot.Group == "" {
+		return "", "", fmt.Errorf("cannot build on %s", slot.Name)
+	}
+	if slot.Owner != player.Id {
+		return "", "", fmt.Errorf("not owner of property")
+	}
+	if !b.ownsGroup(player, slot.Group) {
+		return "", "", fmt.Errorf("must own every property in the %s group", slot.Group)
+	}
+	if slot.State >= HotelState {
+		return "", "", fmt.Errorf("%s already has a hotel", slot.Name)
+	}
+	for _, i := range b.groupSlots(slot.Group) {
+		if b.Slots[i].Mortgaged {
+			return "", "", fmt.Errorf("cannot build while %s is mortgaged", b.Slots[i].Name)
+		}
+		if b.Slots[i].State < slot.State {
+			return "", "", fmt.Errorf("must build evenly across the %s group", slot.Group)
+		}
+	}
+
+	building := "house"
+	if slot.State == HotelState-1 {
+		building = "hotel"
+		if b.Hotels == 0 {
+			return "", "", fmt.Errorf("the bank has no hotels left")
+		}
+	} else if b.Houses == 0 {
+		return "", "", fmt.Errorf("the bank has no houses left")
+	}
+
+	cost := b.HouseCosts[slot.Group]
+	if err := b.transact(Transaction{From: player, Money: cost}); err != nil {
+		return "", "", err
+	}
+	if building == "hotel" {
+		// The houses go back to the bank when the hotel goes up
+		b.Hotels--
+		b.Houses += HotelState - 1
+	} else {
+		b.Houses--
+	}
+	slot.State++
+	b.emit(EventBuildingChanged, player, BuildingPayload{Property: *body.Property, State: slot.State, Amount: cost})
+	return fmt.Sprintf("%s built a %s on %s for %d", player.Name, building, slot.Name, cost), "", nil
+}
+
+// SellHouse sells a house or hotel back to the bank for half the house cost.
+// Selling must also be even across the group, and a hotel can only be broken
+// down while the bank still has the houses to replace it.
+// It must be called with the board locked.
+func (b *Board) SellHouse(player *Player, body GamePropertyBody) (string, string, error) {
+	slot, err := b.slotAt(body.Property)
+	if err != nil {
+		return "", "", err
+	}
+	if slot.Owner != player.Id {
+		return "", "", fmt.Errorf("not owner of property")
+	}
+	if slot.State == 0 {
+		return "", "", fmt.Errorf("%s has no buildings", slot.Name)
+	}
+	for _, i := range b.groupSlots(slot.Group) {
+		if b.Slots[i].State > slot.State {
+			return "", "", fmt.Errorf("must sell evenly across the %s group", slot.Group)
+		}
+	}
+
+	building := "house"
+	if slot.State == HotelState {
+		if b.Houses < HotelState-1 {
+			return "", "", fmt.Errorf("the bank has too few houses to break down the hotel")
+		}
+		b.Hotels++
+		b.Houses -= HotelState - 1
+		building = "hotel"
+	} else {
+		b.Houses++
+	}
+
+	refund := b.HouseCosts[slot.Group] / 2
+	b.transact(Transaction{To: player, Money: refund})
+	slot.State--
+	b.emit(EventBuildingChanged, player, BuildingPayload{Property: *body.Property, State: slot.State, Amount: refund, Sold: true})
+	return fmt.Sprintf("%s sold a %s on %s for %d", player.Name, building, slot.Name, refund), "", nil
+}
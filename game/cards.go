@@ -0,0 +1,230 @@
+package game
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// CardEffect applies a card to the player who drew or played it.
+// It runs with the board locked, as part of the action that drew or played the card.
+// It returns the same broadcast and prompt messages as an action handler.
+type CardEffect func(*Player, *Board) (string, string, error)
+
+// GameUseCardBody names a held card the player wants to play.
+type GameUseCardBody struct {
+	Card IdType `json:"card" validate:"required"`
+}
+
+// Deck is a named pile of cards such as Chance or Community Chest.
+// Cards are referred to by id, the top of the draw pile is its first element.
+type Deck struct {
+	Name     string
+	DrawPile []int
+	Discard  []int
+}
+
+// Shuffle puts the discard pile back into the draw pile and shuffles it.
+func (d *Deck) Shuffle() {
+	d.DrawPile = append(d.DrawPile, d.Discard...)
+	d.Discard = nil
+	rand.Shuffle(len(d.DrawPile), func(i, j int) {
+		d.DrawPile[i], d.DrawPile[j] = d.DrawPile[j], d.DrawPile[i]
+	})
+}
+
+// AddCard registers a card with the board and places it in its deck's draw pile.
+func (b *Board) AddCard(card Card) {
+	b.Lock()
+	defer b.Unlock()
+
+	id := len(b.Cards)
+	card.Id = &id
+	b.Cards = append(b.Cards, &card)
+
+	if b.Decks == nil {
+		b.Decks = make(map[string]*Deck)
+	}
+	deck, ok := b.Decks[card.Deck]
+	if !ok {
+		deck = &Deck{Name: card.Deck}
+		b.Decks[card.Deck] = deck
+	}
+	deck.DrawPile = append(deck.DrawPile, id)
+}
+
+// ShuffleDecks shuffles every deck on the board.
+func (b *Board) ShuffleDecks() {
+	b.Lock()
+	defer b.Unlock()
+	for _, deck := range b.Decks {
+		deck.Shuffle()
+	}
+}
+
+// cardById returns a registered card, or nil for an unknown id.
+func (b *Board) cardById(id IdType) *Card {
+	if id == nil || *id < 0 || *id >= len(b.Cards) {
+		return nil
+	}
+	return b.Cards[*id]
+}
+
+// DrawCard takes the top card of a deck, reshuffling the discard pile when the draw pile runs out.
+func (b *Board) DrawCard(deckName string) (*Card, error) {
+	b.Lock()
+	defer b.Unlock()
+	return b.drawCard(deckName)
+}
+
+// drawCard is DrawCard for callers that already hold the board lock.
+func (b *Board) drawCard(deckName string) (*Card, error) {
+	deck, ok := b.Decks[deckName]
+	if !ok {
+		return nil, fmt.Errorf("no %s deck", deckName)
+	}
+	if len(deck.DrawPile) == 0 {
+		deck.Shuffle()
+	}
+	if len(deck.DrawPile) == 0 {
+		// Every card of the deck is held by players
+		return nil, fmt.Errorf("the %s deck is empty", deckName)
+	}
+	id := deck.DrawPile[0]
+	deck.DrawPile = deck.DrawPile[1:]
+	return b.Cards[id], nil
+}
+
+// discardCard returns a card to the bottom of its deck's discard pile.
+// It does not take the board lock.
+func (b *Board) discardCard(id IdType) {
+	card := b.cardById(id)
+	if card == nil {
+		return
+	}
+	if deck, ok := b.Decks[card.Deck]; ok {
+		deck.Discard = append(deck.Discard, *card.Id)
+	}
+}
+
+// UseCard plays a card from the player's inventory and returns it to its deck.
+func (b *Board) UseCard(player *Player, body GameUseCardBody) (string, string, error) {
+	card := b.cardById(body.Card)
+	if card == nil {
+		return "", "", fmt.Errorf("unknown card")
+	}
+	if card.CanPlay != nil && !card.CanPlay(player, b) {
+		return "", "", fmt.Errorf("%s cannot be played now", card.Name)
+	}
+
+	// Handing the card back to the bank returns it to its deck
+	if err := b.transact(Transaction{From: player, Cards: []IdType{card.Id}}); err != nil {
+		return "", "", err
+	}
+
+	msg, prompt, err := card.Effect(player, b)
+	if err != nil {
+		return "", "", err
+	}
+	// Cards are played before rolling, a Jail Free Card turns the jail decision into a normal turn
+	next := PhasePreRoll
+	if player.InJail {
+		next = PhaseJailDecision
+	}
+	b.settle(next)
+	return msg, prompt, nil
+}
+
+// InJail is a CanPlay check for cards that only help a jailed player.
+func InJail(p *Player, b *Board) bool {
+	return p.InJail
+}
+
+// CollectEffect pays the player from the bank.
+func CollectEffect(amount int) CardEffect {
+	return func(p *Player, b *Board) (string, string, error) {
+		if err := b.transact(Transaction{To: p, Money: amount}); err != nil {
+			return "", "", err
+		}
+		return fmt.Sprintf("%s collected %d", p.Name, amount), "", nil
+	}
+}
+
+// PayEffect charges the player a fine, recording a debt if they cannot pay.
+func PayEffect(amount int) CardEffect {
+	return func(p *Player, b *Board) (string, string, error) {
+		return b.payFine(p, amount, "card fees", EventFinePaid)
+	}
+}
+
+// AdvanceToEffect moves the player forward to a slot, collecting the Go salary on the way.
+func AdvanceToEffect(position int) CardEffect {
+	return func(p *Player, b *Board) (string, string, error) {
+		msg, prompt, err := b.AdvanceTo(p, position)
+		return joinMessages(fmt.Sprintf("%s advanced to %s", p.Name, b.Slots[position].Name), msg), prompt, err
+	}
+}
+
+// MoveBackEffect moves the player back a number of slots, without passing Go.
+func MoveBackEffect(steps int) CardEffect {
+	return func(p *Player, b *Board) (string, string, error) {
+		msg, prompt, err := b.MovePlayer(p, -steps)
+		return joinMessages(fmt.Sprintf("%s went back %d spaces", p.Name, steps), msg), prompt, err
+	}
+}
+
+// PayPerBuildingEffect charges the player for every house and hotel they own.
+func PayPerBuildingEffect(perHouse int, perHotel int) CardEffect {
+	return func(p *Player, b *Board) (string, string, error) {
+		houses, hotels := 0, 0
+		for _, slot := range b.Slots {
+			if slot.Owner != p.Id {
+				continue
+			}
+			if slot.State == HotelState {
+				hotels++
+			} else {
+				houses += slot.State
+			}
+		}
+		return PayEffect(houses*perHouse+hotels*perHotel)(p, b)
+	}
+}
+
+// CollectFromEachPlayerEffect makes every other player pay the player.
+// Anyone who cannot pay owes the amount as a debt.
+func CollectFromEachPlayerEffect(amount int) CardEffect {
+	return func(p *Player, b *Board) (string, string, error) {
+		messages := []string{}
+		for _, other := range b.Players {
+			if other == p {
+				continue
+			}
+			if other.Money < amount {
+				msg, _, _ := b.recordDebt(other, p, amount, DebtKindPayment, "card payments")
+				messages = append(messages, msg)
+				continue
+			}
+			if err := b.transact(Transaction{From: other, To: p, Money: amount}); err != nil {
+				return "", "", err
+			}
+			messages = append(messages, fmt.Sprintf("%s paid %s %d", other.Name, p.Name, amount))
+		}
+		return joinMessages(messages...), "", nil
+	}
+}
+
+// GoToJailEffect sends the player straight to jail.
+func GoToJailEffect() CardEffect {
+	return func(p *Player, b *Board) (string, string, error) {
+		return b.SendToJail(p), b.jailPrompt(p), nil
+	}
+}
+
+// JailFreeEffect releases the player from jail.
+func JailFreeEffect() CardEffect {
+	return func(p *Player, b *Board) (string, string, error) {
+		p.InJail = false
+		p.JailTurns = 0
+		return fmt.Sprintf("%s used a Jail Free Card", p.Name), "", nil
+	}
+}
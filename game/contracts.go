@@ -0,0 +1,179 @@
+package game
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RightKind is a future right on a property that can be traded.
+type RightKind string
+
+const (
+	// RightRentImmunity lets the holder land on the property without paying rent
+	RightRentImmunity RightKind = "rent_immunity"
+	// RightRentShare pays the holder a percentage of the rent collected on the property
+	RightRentShare RightKind = "rent_share"
+)
+
+// TradeRight is a future right offered in a trade. The giving side must own the property.
+type TradeRight struct {
+	Kind     RightKind `json:"kind"`
+	Property IdType    `json:"property"`
+	// Percent is the holder's share of the rent, for rent shares
+	Percent int `json:"percent,omitempty"`
+	// Laps is how many times the holder passes Go before the right runs out
+	Laps int `json:"laps"`
+}
+
+// Contract is a traded right in force.
+// It stays with the property when the property changes hands, and ends when its laps run out.
+type Contract struct {
+	Id       int       `json:"id"`
+	Kind     RightKind `json:"kind"`
+	Property int       `json:"property"`
+	Grantor  IdType    `json:"grantor"`
+	Holder   IdType    `json:"holder"`
+	Percent  int       `json:"percent,omitempty"`
+	LapsLeft int       `json:"lapsLeft"`
+}
+
+// checkRight makes sure a right can be offered by its owner.
+func (b *Board) checkRight(owner *Player, right TradeRight) error {
+	slot, err := b.slotAt(right.Property)
+	if err != nil {
+		return err
+	}
+	if slot.Owner != owner.Id {
+		return fmt.Errorf("%s does not own %s", owner.Name, slot.Name)
+	}
+	if right.Laps <= 0 {
+		return fmt.Errorf("rights must last at least one lap")
+	}
+	switch right.Kind {
+	case RightRentImmunity:
+	case RightRentShare:
+		if right.Percent <= 0 || right.Percent > 100 {
+			return fmt.Errorf("rent share must be between 1 and 100 percent")
+		}
+	default:
+		return fmt.Errorf("unknown right %q", right.Kind)
+	}
+	return nil
+}
+
+// describeRight spells out a right for trade messages.
+func (b *Board) describeRight(right TradeRight) string {
+	name := "a property"
+	if slot, err := b.slotAt(right.Property); err == nil {
+		name = slot.Name
+	}
+	if right.Kind == RightRentShare {
+		return fmt.Sprintf("%d%% of the rent on %s for %d laps", right.Percent, name, right.Laps)
+	}
+	return fmt.Sprintf("no rent on %s for %d laps", name, right.Laps)
+}
+
+// grantRight puts a traded right in force.
+// It must be called with the board locked.
+func (b *Board) grantRight(grantor *Player, holder *Player, right TradeRight) {
+	b.nextContractId++
+	b.Contracts = append(b.Contracts, &Contract{
+		Id:       b.nextContractId,
+		Kind:     right.Kind,
+		Property: *right.Property,
+		Grantor:  grantor.Id,
+		Holder:   holder.Id,
+		Percent:  right.Percent,
+		LapsLeft: right.Laps,
+	})
+}
+
+// contractsOn lists the contracts of a kind in force on a property.
+func (b *Board) contractsOn(position int, kind RightKind) []*Contract {
+	contracts := []*Contract{}
+	for _, contract := range b.Contracts {
+		if contract.Property == position && contract.Kind == kind {
+			contracts = append(contracts, contract)
+		}
+	}
+	return contracts
+}
+
+// countLap runs down the contracts held by a player who just passed Go, ending those out of laps.
+func (b *Board) countLap(player *Player) string {
+	messages := []string{}
+	remaining := []*Contract{}
+	for _, contract := range b.Contracts {
+		if *contract.Holder == *player.Id {
+			contract.LapsLeft--
+		}
+		if contract.LapsLeft <= 0 {
+			messages = append(messages, fmt.Sprintf("%s's %s on %s ended", player.Name,
+				strings.ReplaceAll(string(contract.Kind), "_", " "), b.Slots[contract.Property].Name))
+			continue
+		}
+		remaining = append(remaining, contract)
+	}
+	b.Contracts = remaining
+	return strings.Join(messages, "\n")
+}
+
+// endContracts drops the contracts held by a player, or on properties that went back to the bank.
+// It must be called with the board locked.
+func (b *Board) endContracts() {
+	remaining := []*Contract{}
+	for _, contract := range b.Contracts {
+		if b.GetPlayer(contract.Holder) == nil || b.Slots[contract.Property].Owner == nil {
+			continue
+		}
+		remaining = append(remaining, contract)
+	}
+	b.Contracts = remaining
+}
+
+// payRent charges the player rent on the slot they landed on, honoring traded rights.
+// Rent immunity waives it, rent shares send part of it to their holders.
+// A player who cannot pay owes the whole rent to the owner.
+func (b *Board) payRent(player *Player, owner *Player, position int, rent int) (string, string, error) {
+	slot := b.Slots[position]
+	for _, contract := range b.contractsOn(position, RightRentImmunity) {
+		if *contract.Holder == *player.Id {
+			return fmt.Sprintf("%s landed on %s and pays no rent under contract", player.Name, slot.Name), "", nil
+		}
+	}
+	if player.Money < rent {
+		return b.recordDebt(player, owner, rent, DebtKindRent, "rent")
+	}
+
+	transactions := []Transaction{}
+	messages := []string{}
+	ownerShare, kept := rent, 0
+	for _, contract := range b.contractsOn(position, RightRentShare) {
+		holder := b.GetPlayer(contract.Holder)
+		share := rent * contract.Percent / 100
+		if holder == nil || share == 0 || share > ownerShare {
+			continue
+		}
+		ownerShare -= share
+		if *holder.Id == *player.Id {
+			// Holders landing on the property keep their own share
+			kept += share
+			continue
+		}
+		transactions = append(transactions, Transaction{From: player, To: holder, Money: share})
+		messages = append(messages, fmt.Sprintf("%s gets a %d share", holder.Name, share))
+	}
+	transactions = append(transactions, Transaction{From: player, To: owner, Money: ownerShare})
+	if err := b.transact(transactions...); err != nil {
+		return "", "", err
+	}
+	b.emit(EventRentPaid, player, RentPaidPayload{Property: position, Owner: owner.Id, Amount: rent - kept})
+	if ownerShare == rent {
+		return fmt.Sprintf("%s paid %d rent to %s", player.Name, rent, owner.Name), "", nil
+	}
+	messages = append(messages, fmt.Sprintf("%s gets %d", owner.Name, ownerShare))
+	if kept > 0 {
+		messages = append(messages, fmt.Sprintf("%s keeps their %d share", player.Name, kept))
+	}
+	return fmt.Sprintf("%s paid %d rent on %s: %s", player.Name, rent-kept, slot.Name, strings.Join(messages, ", ")), "", nil
+}
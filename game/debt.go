@@ -0,0 +1,275 @@
+package game
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DebtKind tells what a debt is owed for.
+type DebtKind string
+
+const (
+	DebtKindTax     DebtKind = "tax"
+	DebtKindFine    DebtKind = "fine"
+	DebtKindRent    DebtKind = "rent"
+	DebtKindPayment DebtKind = "payment"
+	DebtKindLoan    DebtKind = "loan"
+)
+
+// Debt is money a player owes but could not pay on the spot.
+// A nil Creditor means the debt is owed to the bank.
+type Debt struct {
+	Debtor   IdType   `json:"debtor"`
+	Creditor IdType   `json:"creditor"`
+	Amount   int      `json:"amount"`
+	Kind     DebtKind `json:"kind"`
+}
+
+// feedsJackpot reports whether paying the debt puts the money in the Free Parking
+// jackpot, as for taxes and fines paid on the spot.
+func (debt *Debt) feedsJackpot() bool {
+	return debt.Creditor == nil && (debt.Kind == DebtKindTax || debt.Kind == DebtKindFine)
+}
+
+// recordDebt notes that the debtor owes money they do not have, and prompts them to raise it.
+// A nil creditor is the bank.
+// It must be called with the board locked.
+func (b *Board) recordDebt(debtor *Player, creditor *Player, amount int, kind DebtKind, reason string) (string, string, error) {
+	debt := &Debt{Debtor: debtor.Id, Amount: amount, Kind: kind}
+	creditorName := "the bank"
+	if creditor != nil {
+		debt.Creditor = creditor.Id
+		creditorName = creditor.Name
+	}
+	b.Debts = append(b.Debts, debt)
+	b.emit(EventDebtRecorded, debtor, *debt)
+
+	return fmt.Sprintf("%s owes %s %d in %s and cannot pay", debtor.Name, creditorName, amount, reason),
+		fmt.Sprintf("Raise %d by mortgaging, selling houses or trading, then pay your debt or declare bankruptcy", amount-debtor.Money), nil
+}
+
+// debtOf returns the first outstanding debt of a player, or nil.
+func (b *Board) debtOf(player *Player) *Debt {
+	for _, debt := range b.Debts {
+		if *debt.Debtor == *player.Id {
+			return debt
+		}
+	}
+	return nil
+}
+
+// PayDebt settles the player's outstanding debts once they have raised enough money.
+// It must be called with the board locked.
+func (b *Board) PayDebt(player *Player) (string, string, error) {
+	owed := 0
+	for _, debt := range b.Debts {
+		if *debt.Debtor == *player.Id {
+			owed += debt.Amount
+		}
+	}
+	if owed == 0 {
+		return "", "", fmt.Errorf("no debt to pay")
+	}
+	if player.Money < owed {
+		return "", "", fmt.Errorf("insufficient funds, %d more needed", owed-player.Money)
+	}
+
+	messages := []string{}
+	remaining := []*Debt{}
+	transactions := []Transaction{}
+	toBank := 0
+	for _, debt := range b.Debts {
+		if *debt.Debtor != *player.Id {
+			remaining = append(remaining, debt)
+			continue
+		}
+		creditor := b.GetPlayer(debt.Creditor)
+		if debt.feedsJackpot() {
+			toBank += debt.Amount
+		}
+		transactions = append(transactions, Transaction{From: player, To: creditor, Money: debt.Amount})
+		messages = append(messages, fmt.Sprintf("%s paid their debt of %d to %s", player.Name, debt.Amount, partyName(creditor)))
+	}
+	if err := b.transact(transactions...); err != nil {
+		return "", "", err
+	}
+	b.addToJackpot(toBank)
+	b.Debts = remaining
+	b.refreshPhase()
+	return strings.Join(messages, "\n"), "", nil
+}
+
+// liquidationValue is the most cash a player could raise by selling every
+// building and mortgaging every property.
+func (b *Board) liquidationValue(player *Player) int {
+	value := player.Money
+	for _, slot := range b.Slots {
+		if slot.Owner != player.Id {
+			continue
+		}
+		value += b.buildingValue(&slot)
+		if !slot.Mortgaged {
+			value += slot.MortgageValue()
+		}
+	}
+	return value
+}
+
+// settleDebts is run before a turn can end. Debtors who could never raise
+// what they owe go bankrupt; anyone else still owing blocks the turn.
+func (b *Board) settleDebts() (string, error) {
+	messages := []string{}
+	// A debtor has to raise everything they owe, so their debts are weighed together
+	owed := map[int]int{}
+	debtors := []IdType{}
+	for _, debt := range b.Debts {
+		if _, ok := owed[*debt.Debtor]; !ok {
+			debtors = append(debtors, debt.Debtor)
+		}
+		owed[*debt.Debtor] += debt.Amount
+	}
+	for _, id := range debtors {
+		debtor := b.GetPlayer(id)
+		if debtor == nil || b.liquidationValue(debtor) >= owed[*id] {
+			continue
+		}
+		// As when they declare it, the bankrupt player's assets go to whoever they owe first
+		var creditor *Player
+		if debt := b.debtOf(debtor); debt != nil {
+			creditor = b.GetPlayer(debt.Creditor)
+		}
+		msg, err := b.Bankrupt(debtor, creditor)
+		if err != nil {
+			return strings.Join(messages, "\n"), err
+		}
+		messages = append(messages, msg)
+	}
+	if len(messages) > 0 {
+		return strings.Join(messages, "\n"), nil
+	}
+	if len(b.Debts) > 0 {
+		debt := b.Debts[0]
+		return "", fmt.Errorf("waiting for %s to pay a debt of %d", b.GetPlayer(debt.Debtor).Name, debt.Amount)
+	}
+	return "", nil
+}
+
+// buildingValue is what the bank pays back for the buildings on a slot, half their cost.
+func (b *Board) buildingValue(slot *Slot) int {
+	return slot.State * b.HouseCosts[slot.Group] / 2
+}
+
+// returnBuildings sells the buildings on a slot back to the bank's supply as they stand, at half their cost.
+// It is part of transact, which checks the sale first.
+// It must be called with the board locked.
+func (b *Board) returnBuildings(player *Player, position int) {
+	slot := &b.Slots[position]
+	if slot.State == 0 {
+		return
+	}
+	if slot.State == HotelState {
+		b.Hotels++
+	} else {
+		b.Houses += slot.State
+	}
+	refund := b.buildingValue(slot)
+	b.TransferBankToPlayer(player, refund)
+	slot.State = 0
+	b.emit(EventBuildingChanged, player, BuildingPayload{Property: position, Amount: refund, Sold: true})
+}
+
+// DeclareBankruptcy gives up the game, handing everything to whoever the player owes.
+func (b *Board) DeclareBankruptcy(player *Player) (string, string, error) {
+	var creditor *Player
+	if debt := b.debtOf(player); debt != nil && debt.Creditor != nil {
+		creditor = b.GetPlayer(debt.Creditor)
+	}
+	msg, err := b.Bankrupt(player, creditor)
+	return msg, "", err
+}
+
+// Bankrupt eliminates a player. A creditor player takes their cash, properties
+// (mortgages included) and cards, with buildings sold back to the bank first.
+// When the bank is the creditor (nil), properties return to the bank unmortgaged
+// and go up for auction one after another.
+// Nothing changes if the assets cannot be handed over.
+// It must be called with the board locked.
+func (b *Board) Bankrupt(player *Player, creditor *Player) (string, error) {
+	messages := []string{}
+	properties := []IdType{}
+	buildings := []IdType{}
+	refund := 0
+	for i := range b.Slots {
+		slot := &b.Slots[i]
+		if slot.Owner != player.Id {
+			continue
+		}
+		position := i
+		properties = append(properties, &position)
+		if slot.State > 0 {
+			buildings = append(buildings, &position)
+			refund += b.buildingValue(slot)
+		}
+	}
+
+	// Everything the player has left, the building refunds included, goes over in one transaction
+	assets := Transaction{
+		From:       player,
+		To:         creditor,
+		Money:      player.Money + refund,
+		Properties: properties,
+		Cards:      append([]IdType{}, player.Inventory...),
+		Buildings:  buildings,
+	}
+	if err := b.transact(assets); err != nil {
+		return "", err
+	}
+	if creditor != nil {
+		messages = append(messages, fmt.Sprintf("%s went bankrupt, %s takes over all their assets", player.Name, creditor.Name))
+	} else {
+		// The cash covers the taxes and fines owed first, which feed the jackpot as when a debt is paid
+		owed := 0
+		for _, debt := range b.Debts {
+			if *debt.Debtor == *player.Id && debt.feedsJackpot() {
+				owed += debt.Amount
+			}
+		}
+		b.addToJackpot(min(owed, assets.Money))
+		for _, property := range properties {
+			b.Slots[*property].Mortgaged = false
+			b.AuctionQueue = append(b.AuctionQueue, *property)
+		}
+		messages = append(messages, fmt.Sprintf("%s went bankrupt to the bank", player.Name))
+	}
+
+	// Debts owed by the player die with them, debts owed to them go to the bank
+	// without feeding the jackpot, as they keep their kind
+	remaining := []*Debt{}
+	for _, debt := range b.Debts {
+		if *debt.Debtor == *player.Id {
+			continue
+		}
+		if debt.Creditor != nil && *debt.Creditor == *player.Id {
+			debt.Creditor = nil
+		}
+		remaining = append(remaining, debt)
+	}
+	b.Debts = remaining
+
+	var creditorId IdType
+	if creditor != nil {
+		creditorId = creditor.Id
+	}
+	b.emit(EventBankrupt, player, BankruptPayload{Creditor: creditorId})
+	b.removePlayer(player)
+	b.endContracts()
+	b.endLoans()
+	if len(b.Players) == 1 {
+		messages = append(messages, fmt.Sprintf("%s wins the game!", b.Players[0].Name))
+	}
+	if b.Auction == nil && len(b.AuctionQueue) > 0 {
+		messages = append(messages, b.startNextAuction())
+	}
+	b.refreshPhase()
+	return strings.Join(messages, "\n"), nil
+}
@@ -0,0 +1,80 @@
+package game
+
+import "testing"
+
+func TestPaidDebtsFeedJackpot(t *testing.T) {
+	tests := []struct {
+		name string
+		kind DebtKind
+		// bankrupt has the creditor go bankrupt before the debt is paid
+		bankrupt    bool
+		wantJackpot int
+	}{
+		{name: "tax", kind: DebtKindTax, wantJackpot: 100},
+		{name: "fine", kind: DebtKindFine, wantJackpot: 100},
+		{name: "rent", kind: DebtKindRent},
+		{name: "rent owed to a bankrupt player", kind: DebtKindRent, bankrupt: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewBoard()
+			b.Rules.FreeParkingJackpot = true
+			a, c, d := b.AddPlayer("a"), b.AddPlayer("c"), b.AddPlayer("d")
+			b.startTurn()
+
+			creditor := c
+			if tt.kind == DebtKindTax || tt.kind == DebtKindFine {
+				creditor = nil
+			}
+			d.Money = 50
+			b.recordDebt(d, creditor, 100, tt.kind, string(tt.kind))
+			if tt.bankrupt {
+				if _, err := b.Bankrupt(c, a); err != nil {
+					t.Fatal(err)
+				}
+			}
+			d.Money = 100
+			if _, _, err := b.PayDebt(d); err != nil {
+				t.Fatal(err)
+			}
+			if b.Jackpot != tt.wantJackpot {
+				t.Errorf("jackpot is %d, want %d", b.Jackpot, tt.wantJackpot)
+			}
+		})
+	}
+}
+
+func TestSettleDebtsWeighsEverythingOwed(t *testing.T) {
+	tests := []struct {
+		name         string
+		money        int
+		wantBankrupt bool
+	}{
+		{name: "can raise the total", money: 120},
+		{name: "can raise each debt but not the total", money: 100, wantBankrupt: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewBoard()
+			a, c, d := b.AddPlayer("a"), b.AddPlayer("c"), b.AddPlayer("d")
+			b.startTurn()
+			d.Money = 0
+			b.recordDebt(d, a, 60, DebtKindRent, "rent")
+			b.recordDebt(d, c, 60, DebtKindRent, "rent")
+			d.Money = tt.money
+
+			_, err := b.settleDebts()
+			bankrupt := b.GetPlayer(d.Id) == nil
+			if bankrupt != tt.wantBankrupt {
+				t.Fatalf("bankrupt = %v, want %v", bankrupt, tt.wantBankrupt)
+			}
+			if bankrupt {
+				if err != nil || len(b.Debts) != 0 || a.Money != DefaultRules().StartingMoney+tt.money {
+					t.Errorf("error %v with %d debts left, first creditor has %d", err, len(b.Debts), a.Money)
+				}
+			} else if err == nil {
+				t.Error("turn can end with debts unpaid")
+			}
+		})
+	}
+}
@@ -0,0 +1,321 @@
+package game
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	json "github.com/json-iterator/go"
+	"gopkg.in/yaml.v3"
+)
+
+// BoardDefinition describes a board in a JSON or YAML file, so new boards
+// can ship without recompiling the server.
+type BoardDefinition struct {
+	Name       string           `json:"name" yaml:"name"`
+	Slots      []SlotDefinition `json:"slots" yaml:"slots"`
+	HouseCosts map[string]int   `json:"houseCosts" yaml:"houseCosts"`
+	Decks      []DeckDefinition `json:"decks" yaml:"decks"`
+}
+
+// SlotDefinition describes one slot. Slot 0 is always Go.
+type SlotDefinition struct {
+	Name  string   `json:"name" yaml:"name"`
+	Type  Slottype `json:"type" yaml:"type"`
+	Price int      `json:"price,omitempty" yaml:"price,omitempty"`
+	Group string   `json:"group,omitempty" yaml:"group,omitempty"`
+	// Rent lists the rent tiers, see calculateRent for their meaning per slot type.
+	// Properties have five, railroads and utilities one for each of their kind on the board.
+	Rent []int `json:"rent,omitempty" yaml:"rent,omitempty"`
+	// Tax is the flat amount charged by a tax slot
+	Tax int `json:"tax,omitempty" yaml:"tax,omitempty"`
+	// TaxMode picks flat (default), percent, lesser, greater or choice, TaxPercent is the share of net worth
+	TaxMode    TaxMode `json:"taxMode,omitempty" yaml:"taxMode,omitempty"`
+	TaxPercent int     `json:"taxPercent,omitempty" yaml:"taxPercent,omitempty"`
+	// Deck is the deck a card slot draws from
+	Deck string `json:"deck,omitempty" yaml:"deck,omitempty"`
+	// Kind tells neutral slots apart, such as go or free_parking
+	Kind NeutralKind `json:"kind,omitempty" yaml:"kind,omitempty"`
+}
+
+// DeckDefinition describes a named deck of cards.
+type DeckDefinition struct {
+	Name  string           `json:"name" yaml:"name"`
+	Cards []CardDefinition `json:"cards" yaml:"cards"`
+}
+
+// CardEffectType names one of the standard card effects.
+type CardEffectType string
+
+const (
+	CardEffectCollect         CardEffectType = "collect"
+	CardEffectPay             CardEffectType = "pay"
+	CardEffectAdvanceTo       CardEffectType = "advance_to"
+	CardEffectMoveBack        CardEffectType = "move_back"
+	CardEffectPayPerBuilding  CardEffectType = "pay_per_building"
+	CardEffectCollectFromEach CardEffectType = "collect_from_each"
+	CardEffectGoToJail        CardEffectType = "go_to_jail"
+	CardEffectJailFree        CardEffectType = "jail_free"
+)
+
+// CardDefinition describes a card and the parameters of its effect.
+type CardDefinition struct {
+	Name        string         `json:"name" yaml:"name"`
+	Description string         `json:"description" yaml:"description"`
+	Effect      CardEffectType `json:"effect" yaml:"effect"`
+	// Amount is collected or paid by collect, pay and collect_from_each
+	Amount int `json:"amount,omitempty" yaml:"amount,omitempty"`
+	// Target is the slot name advance_to moves to
+	Target string `json:"target,omitempty" yaml:"target,omitempty"`
+	// Steps is how far move_back moves
+	Steps    int `json:"steps,omitempty" yaml:"steps,omitempty"`
+	PerHouse int `json:"perHouse,omitempty" yaml:"perHouse,omitempty"`
+	PerHotel int `json:"perHotel,omitempty" yaml:"perHotel,omitempty"`
+}
+
+// LoadBoardDefinition reads a board definition from a .json, .yaml or .yml file and validates it.
+func LoadBoardDefinition(path string) (*BoardDefinition, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read board file: %w", err)
+	}
+
+	var def BoardDefinition
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.Unmarshal(data, &def)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &def)
+	default:
+		return nil, fmt.Errorf("unsupported board file type %q", filepath.Ext(path))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse board file %s: %w", path, err)
+	}
+
+	if err := def.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid board %s: %w", path, err)
+	}
+	return &def, nil
+}
+
+// LoadBoard creates a board from a definition file.
+func LoadBoard(path string) (*Board, error) {
+	def, err := LoadBoardDefinition(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewBoardFromDefinition(def)
+}
+
+// position returns the index of the slot with the given name, or -1.
+func (def *BoardDefinition) position(name string) int {
+	for i, slot := range def.Slots {
+		if slot.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// Validate checks that the definition describes a playable board.
+func (def *BoardDefinition) Validate() error {
+	if len(def.Slots) < 2 {
+		return fmt.Errorf("a board needs at least two slots")
+	}
+	if def.Slots[0].Type != SlotTypeNeutral {
+		return fmt.Errorf("slot 0 must be the neutral Go slot")
+	}
+
+	decks := map[string]bool{}
+	for _, deck := range def.Decks {
+		if deck.Name == "" {
+			return fmt.Errorf("deck without a name")
+		}
+		if decks[deck.Name] {
+			return fmt.Errorf("duplicate deck %q", deck.Name)
+		}
+		decks[deck.Name] = true
+		// Kept cards leave the deck, once players hold them all a draw would find nothing
+		kept := 0
+		for _, card := range deck.Cards {
+			if card.Effect == CardEffectJailFree {
+				kept++
+			}
+		}
+		if len(deck.Cards) == 0 {
+			return fmt.Errorf("deck %q has no cards", deck.Name)
+		}
+		if kept == len(deck.Cards) {
+			return fmt.Errorf("deck %q needs a card that is not kept", deck.Name)
+		}
+	}
+
+	names := map[string]bool{}
+	jails := 0
+	counts := map[Slottype]int{}
+	for i, slot := range def.Slots {
+		if slot.Name == "" {
+			return fmt.Errorf("slot %d has no name", i)
+		}
+		if names[slot.Name] {
+			return fmt.Errorf("slot %d: duplicate name %q", i, slot.Name)
+		}
+		names[slot.Name] = true
+		counts[slot.Type]++
+		if len(slot.Rent) > 5 {
+			return fmt.Errorf("slot %d (%s): at most five rent tiers", i, slot.Name)
+		}
+
+		switch slot.Type {
+		case SlotTypeProperty:
+			if slot.Group == "" {
+				return fmt.Errorf("slot %d (%s): property needs a color group", i, slot.Name)
+			}
+			if _, ok := def.HouseCosts[slot.Group]; !ok {
+				return fmt.Errorf("slot %d (%s): no house cost for group %q", i, slot.Name, slot.Group)
+			}
+			// One tier for the bare lot and one for each building up to the hotel
+			if len(slot.Rent) != HotelState+1 {
+				return fmt.Errorf("slot %d (%s): property needs five rent tiers", i, slot.Name)
+			}
+			fallthrough
+		case SlotTypeRailroad, SlotTypeUtility:
+			if slot.Price <= 0 {
+				return fmt.Errorf("slot %d (%s): needs a price", i, slot.Name)
+			}
+			if len(slot.Rent) == 0 {
+				return fmt.Errorf("slot %d (%s): needs rent", i, slot.Name)
+			}
+		case SlotTypeCard:
+			if !decks[slot.Deck] {
+				return fmt.Errorf("slot %d (%s): unknown deck %q", i, slot.Name, slot.Deck)
+			}
+		case SlotTypeTax:
+			needsFlat, needsPercent := true, true
+			switch slot.TaxMode {
+			case "", TaxModeFlat:
+				needsPercent = false
+			case TaxModePercent:
+				needsFlat = false
+			case TaxModeLesser, TaxModeGreater, TaxModeChoice:
+			default:
+				return fmt.Errorf("slot %d (%s): unknown tax mode %q", i, slot.Name, slot.TaxMode)
+			}
+			if needsFlat && slot.Tax <= 0 {
+				return fmt.Errorf("slot %d (%s): needs a tax amount", i, slot.Name)
+			}
+			if needsPercent && (slot.TaxPercent <= 0 || slot.TaxPercent > 100) {
+				return fmt.Errorf("slot %d (%s): needs a tax percent between 1 and 100", i, slot.Name)
+			}
+		case SlotTypeJail:
+			jails++
+		case SlotTypeGoToJail:
+		case SlotTypeNeutral:
+			switch slot.Kind {
+			case "", NeutralKindGo, NeutralKindFreeParking:
+			default:
+				return fmt.Errorf("slot %d (%s): unknown neutral kind %q", i, slot.Name, slot.Kind)
+			}
+		default:
+			return fmt.Errorf("slot %d (%s): unknown type %q", i, slot.Name, slot.Type)
+		}
+	}
+	// Speeding sends players to jail on any board
+	if jails != 1 {
+		return fmt.Errorf("a board needs exactly one jail slot")
+	}
+	// Railroad and utility rent goes by how many of them the owner has
+	for i, slot := range def.Slots {
+		if (slot.Type == SlotTypeRailroad || slot.Type == SlotTypeUtility) && len(slot.Rent) < counts[slot.Type] {
+			return fmt.Errorf("slot %d (%s): needs a rent tier for each of the %d %s slots", i, slot.Name, counts[slot.Type], slot.Type)
+		}
+	}
+
+	for _, deck := range def.Decks {
+		for _, card := range deck.Cards {
+			switch card.Effect {
+			case CardEffectAdvanceTo:
+				if def.position(card.Target) == -1 {
+					return fmt.Errorf("card %q: unknown target slot %q", card.Name, card.Target)
+				}
+			case CardEffectMoveBack:
+				if card.Steps <= 0 {
+					return fmt.Errorf("card %q: needs steps", card.Name)
+				}
+			case CardEffectCollect, CardEffectPay, CardEffectCollectFromEach:
+				if card.Amount <= 0 {
+					return fmt.Errorf("card %q: needs an amount", card.Name)
+				}
+			case CardEffectPayPerBuilding, CardEffectJailFree, CardEffectGoToJail:
+			default:
+				return fmt.Errorf("card %q: unknown effect %q", card.Name, card.Effect)
+			}
+		}
+	}
+	return nil
+}
+
+// cardEffect builds the effect a card definition names.
+func (def *BoardDefinition) cardEffect(card CardDefinition) CardEffect {
+	switch card.Effect {
+	case CardEffectCollect:
+		return CollectEffect(card.Amount)
+	case CardEffectPay:
+		return PayEffect(card.Amount)
+	case CardEffectAdvanceTo:
+		return AdvanceToEffect(def.position(card.Target))
+	case CardEffectMoveBack:
+		return MoveBackEffect(card.Steps)
+	case CardEffectPayPerBuilding:
+		return PayPerBuildingEffect(card.PerHouse, card.PerHotel)
+	case CardEffectCollectFromEach:
+		return CollectFromEachPlayerEffect(card.Amount)
+	case CardEffectGoToJail:
+		return GoToJailEffect()
+	default:
+		return JailFreeEffect()
+	}
+}
+
+// NewBoardFromDefinition creates a board from a validated definition.
+func NewBoardFromDefinition(def *BoardDefinition) (*Board, error) {
+	if err := def.Validate(); err != nil {
+		return nil, err
+	}
+
+	slots := make([]Slot, len(def.Slots))
+	for i, s := range def.Slots {
+		slot := Slot{Name: s.Name, Type: s.Type, Price: s.Price, Group: s.Group, Deck: s.Deck, Kind: s.Kind}
+		if s.Type == SlotTypeTax {
+			slot.Price = s.Tax
+			slot.TaxMode = s.TaxMode
+			slot.TaxPercent = s.TaxPercent
+		}
+		rents := []*int{&slot.Rent1, &slot.Rent2, &slot.Rent3, &slot.Rent4, &slot.Rent5}
+		for tier, rent := range s.Rent {
+			*rents[tier] = rent
+		}
+		slots[i] = slot
+	}
+
+	houseCosts := make(map[string]int, len(def.HouseCosts))
+	for group, cost := range def.HouseCosts {
+		houseCosts[group] = cost
+	}
+
+	cards := []Card{}
+	for _, deck := range def.Decks {
+		for _, c := range deck.Cards {
+			card := Card{Name: c.Name, Description: c.Description, Deck: deck.Name, Effect: def.cardEffect(c)}
+			if c.Effect == CardEffectJailFree {
+				card.Keep = true
+				card.CanPlay = InJail
+			}
+			cards = append(cards, card)
+		}
+	}
+
+	return newBoard(slots, houseCosts, cards), nil
+}
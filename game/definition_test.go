@@ -0,0 +1,53 @@
+package game
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		edit func(def *BoardDefinition)
+		ok   bool
+	}{
+		{name: "shipped board", edit: func(def *BoardDefinition) {}, ok: true},
+		{name: "no jail", edit: func(def *BoardDefinition) {
+			def.Slots = slices.DeleteFunc(def.Slots, func(slot SlotDefinition) bool {
+				return slot.Type == SlotTypeJail || slot.Type == SlotTypeGoToJail
+			})
+			for i := range def.Decks {
+				def.Decks[i].Cards = slices.DeleteFunc(def.Decks[i].Cards, func(card CardDefinition) bool {
+					return card.Effect == CardEffectGoToJail
+				})
+			}
+		}},
+		{name: "property with four rent tiers", edit: func(def *BoardDefinition) {
+			def.Slots[1].Rent = def.Slots[1].Rent[:4]
+		}},
+		{name: "more railroads than rent tiers", edit: func(def *BoardDefinition) {
+			for range 3 {
+				railroad := def.Slots[slices.IndexFunc(def.Slots, func(slot SlotDefinition) bool { return slot.Type == SlotTypeRailroad })]
+				railroad.Name += "'"
+				def.Slots = append(def.Slots, railroad)
+			}
+		}},
+		{name: "more utilities than rent tiers", edit: func(def *BoardDefinition) {
+			utility := def.Slots[slices.IndexFunc(def.Slots, func(slot SlotDefinition) bool { return slot.Type == SlotTypeUtility })]
+			utility.Name += "'"
+			def.Slots = append(def.Slots, utility)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			def, err := LoadBoardDefinition("../boards/dhaka.yaml")
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.edit(def)
+			if err := def.Validate(); (err == nil) != tt.ok {
+				t.Errorf("Validate error = %v, want ok %v", err, tt.ok)
+			}
+		})
+	}
+}
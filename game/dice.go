@@ -0,0 +1,31 @@
+package game
+
+import "fmt"
+
+// MaxConsecutiveDoubles is the number of doubles in a row that sends a player to jail for speeding.
+const MaxConsecutiveDoubles = 3
+
+// DiceRoll holds the faces of a two-dice roll.
+type DiceRoll struct {
+	Die1 int `json:"die1"`
+	Die2 int `json:"die2"`
+}
+
+// Total returns the number of steps the roll moves a player.
+func (d DiceRoll) Total() int {
+	return d.Die1 + d.Die2
+}
+
+// IsDouble reports whether both dice show the same face.
+func (d DiceRoll) IsDouble() bool {
+	return d.Die1 == d.Die2
+}
+
+// rollMessage describes a roll so clients can animate both dice.
+func rollMessage(player *Player, roll DiceRoll) string {
+	msg := fmt.Sprintf("%s rolled %d and %d (%d)", player.Name, roll.Die1, roll.Die2, roll.Total())
+	if roll.IsDouble() {
+		msg += " - doubles!"
+	}
+	return msg
+}
@@ -0,0 +1,210 @@
+package game
+
+import "fmt"
+
+// EventVersion is the version of the event envelope. It is bumped whenever an
+// event type or payload changes in a way older clients cannot read.
+const EventVersion = 1
+
+// EventType tells clients what happened and how to read the payload.
+type EventType string
+
+const (
+	// EventMessage carries the text of an action for everyone, EventPrompt asks
+	// the acting player for a decision and EventError tells them what went wrong
+	EventMessage EventType = "message"
+	EventPrompt  EventType = "prompt"
+	EventError   EventType = "error"
+	// EventNotice is a private message for a single player
+	EventNotice EventType = "notice"
+
+	EventDiceRolled     EventType = "dice_rolled"
+	EventPlayerMoved    EventType = "player_moved"
+	EventPropertyBought EventType = "property_bought"
+	EventRentPaid       EventType = "rent_paid"
+	EventTurnChanged    EventType = "turn_changed"
+	EventPlayerJailed   EventType = "player_jailed"
+	EventCardDrawn      EventType = "card_drawn"
+	EventTradeProposed  EventType = "trade_proposed"
+	EventTradeResolved  EventType = "trade_resolved"
+	EventAuctionStarted EventType = "auction_started"
+	EventBidPlaced      EventType = "bid_placed"
+	EventAuctionClosed  EventType = "auction_closed"
+	EventDebtRecorded   EventType = "debt_recorded"
+	EventBankrupt       EventType = "player_bankrupt"
+	EventLoanUpdated    EventType = "loan_updated"
+
+	EventPropertyMortgaged   EventType = "property_mortgaged"
+	EventPropertyUnmortgaged EventType = "property_unmortgaged"
+	EventBuildingChanged     EventType = "building_changed"
+	EventTaxPaid             EventType = "tax_paid"
+	EventFinePaid            EventType = "fine_paid"
+	EventSalaryCollected     EventType = "salary_collected"
+	EventJackpotWon          EventType = "jackpot_won"
+
+	// EventState carries a whole Snapshot, EventStateDiff a StateDiff after every change
+	EventState     EventType = "state"
+	EventStateDiff EventType = "state_diff"
+)
+
+// Event is the envelope of everything the server tells its clients.
+type Event struct {
+	Version int       `json:"v"`
+	Type    EventType `json:"type"`
+	// Player is who the event is about, if anyone
+	Player  IdType      `json:"player,omitempty"`
+	Payload interface{} `json:"payload,omitempty"`
+	// Text is the human-readable version of the event, for clients that only show a log
+	Text string `json:"text,omitempty"`
+	// Target is the only player the event is delivered to, nil means everyone
+	Target IdType `json:"-"`
+}
+
+type (
+	// DiceRolledPayload is the payload of dice_rolled.
+	DiceRolledPayload struct {
+		DiceRoll
+		Total   int  `json:"total"`
+		Doubles bool `json:"doubles"`
+	}
+	// PlayerMovedPayload is the payload of player_moved, Salary is the Go salary collected on the way.
+	PlayerMovedPayload struct {
+		From     int  `json:"from"`
+		To       int  `json:"to"`
+		PassedGo bool `json:"passedGo"`
+		Salary   int  `json:"salary,omitempty"`
+	}
+	// PropertyPayload is the payload of property_bought and auction_closed.
+	// A closed auction without a buyer has no price.
+	PropertyPayload struct {
+		Property int `json:"property"`
+		Price    int `json:"price,omitempty"`
+	}
+	// RentPaidPayload is the payload of rent_paid, Amount is what the player paid in total.
+	RentPaidPayload struct {
+		Property int    `json:"property"`
+		Owner    IdType `json:"owner"`
+		Amount   int    `json:"amount"`
+	}
+	// TurnChangedPayload is the payload of turn_changed.
+	TurnChangedPayload struct {
+		TurnNumber int       `json:"turnNumber"`
+		Phase      TurnPhase `json:"phase"`
+	}
+	// CardDrawnPayload is the payload of card_drawn.
+	CardDrawnPayload struct {
+		Card IdType `json:"card"`
+		Name string `json:"name"`
+		Deck string `json:"deck"`
+		Keep bool   `json:"keep"`
+	}
+	// TradeResolvedPayload is the payload of trade_resolved.
+	TradeResolvedPayload struct {
+		TradeId IdType      `json:"tradeId"`
+		Outcome TradeStatus `json:"outcome"`
+	}
+	// BidPayload is the payload of bid_placed.
+	BidPayload struct {
+		Property int `json:"property"`
+		Amount   int `json:"amount"`
+	}
+	// BankruptPayload is the payload of player_bankrupt, a nil Creditor is the bank.
+	BankruptPayload struct {
+		Creditor IdType `json:"creditor"`
+	}
+	// MortgagePayload is the payload of property_mortgaged and property_unmortgaged,
+	// Amount is what the bank lent or what lifting the mortgage cost.
+	MortgagePayload struct {
+		Property int `json:"property"`
+		Amount   int `json:"amount"`
+	}
+	// BuildingPayload is the payload of building_changed. State is the property's
+	// buildings after the change as in Slot.State, Amount what the player paid,
+	// or was paid for Sold buildings.
+	BuildingPayload struct {
+		Property int  `json:"property"`
+		State    int  `json:"state"`
+		Amount   int  `json:"amount"`
+		Sold     bool `json:"sold"`
+	}
+	// FeePayload is the payload of tax_paid and fine_paid. Slot is where the
+	// player was charged, Jackpot tells whether the money went to the Free Parking pot.
+	FeePayload struct {
+		Slot    int    `json:"slot"`
+		Amount  int    `json:"amount"`
+		Reason  string `json:"reason"`
+		Jackpot bool   `json:"jackpot"`
+	}
+	// SalaryPayload is the payload of salary_collected, Landed is set for a landing right on Go.
+	SalaryPayload struct {
+		Amount int  `json:"amount"`
+		Landed bool `json:"landed"`
+	}
+	// JackpotPayload is the payload of jackpot_won.
+	JackpotPayload struct {
+		Slot   int `json:"slot"`
+		Amount int `json:"amount"`
+	}
+)
+
+// NewEvent wraps a payload in an event envelope of the current version.
+func NewEvent(eventType EventType, player IdType, payload interface{}) Event {
+	return Event{Version: EventVersion, Type: eventType, Player: player, Payload: payload}
+}
+
+// TextEvent is an event carrying only text, such as a message, prompt or error.
+func TextEvent(eventType EventType, target IdType, text string) Event {
+	return Event{Version: EventVersion, Type: eventType, Text: text, Target: target}
+}
+
+// emit queues an event for everyone, to be picked up with DrainEvents.
+// It does not take the board lock.
+func (b *Board) emit(eventType EventType, player *Player, payload interface{}) {
+	var id IdType
+	if player != nil {
+		id = player.Id
+	}
+	b.events = append(b.events, NewEvent(eventType, id, payload))
+}
+
+// notify queues a private notice for the player, to be picked up with DrainEvents.
+// It must be called with the board locked.
+func (b *Board) notify(player IdType, format string, args ...interface{}) {
+	if player == nil {
+		return
+	}
+	b.events = append(b.events, TextEvent(EventNotice, player, fmt.Sprintf(format, args...)))
+}
+
+// DrainEvents returns the queued events and clears the queue.
+func (b *Board) DrainEvents() []Event {
+	b.Lock()
+	defer b.Unlock()
+	return b.drainEvents()
+}
+
+// drainEvents is DrainEvents for callers that already hold the board lock.
+func (b *Board) drainEvents() []Event {
+	events := b.events
+	b.events = nil
+	return events
+}
+
+// actionEvents turns what an action handler returned into events: everything
+// emitted on the way including the state diff, then its message for everyone
+// and its prompt or error for the player.
+// It must be called with the board locked.
+func (b *Board) actionEvents(player *Player, msg string, prompt string, err error) []Event {
+	b.commitState()
+	events := b.drainEvents()
+	if msg != "" {
+		events = append(events, TextEvent(EventMessage, nil, msg))
+	}
+	if prompt != "" {
+		events = append(events, TextEvent(EventPrompt, player.Id, prompt))
+	}
+	if err != nil {
+		events = append(events, TextEvent(EventError, player.Id, err.Error()))
+	}
+	return events
+}
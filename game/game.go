@@ -4,46 +4,26 @@ package game
 import (
 	"fmt"
 	"math/rand"
+	"strings"
 	"sync"
 	"time"
 )
 
-// TradeHistoryEntry records the details of a completed trade between players.
+// TradeHistoryEntry records the details of a resolved trade between players and how it ended.
 type TradeHistoryEntry struct {
+	TradeId       IdType
 	RequesterName string
 	ResponderName string
 	Give          TradeDetails
 	Take          TradeDetails
+	Legs          []TradeLeg
+	Outcome       TradeStatus
 	Timestamp     string
 }
 
 // TransferCards transfers cards from one player to another.
 func (b *Board) TransferCards(sender *Player, receiver *Player, cards ...IdType) error {
-	b.Lock()
-	defer b.Unlock()
-
-	for _, card := range cards {
-		// Check if the sender has the card
-		cardIndex := -1
-		for i, c := range sender.Inventory {
-			if c == card {
-				cardIndex = i
-				break
-			}
-		}
-
-		if cardIndex == -1 {
-			return fmt.Errorf("card not found in sender's inventory")
-		}
-
-		// Remove the card from the sender's inventory
-		sender.Inventory = append(sender.Inventory[:cardIndex], sender.Inventory[cardIndex+1:]...)
-
-		// Add the card to the receiver's inventory
-		receiver.Inventory = append(receiver.Inventory, card)
-	}
-
-	return nil
+	return b.Transact(Transaction{From: sender, To: receiver, Cards: cards})
 }
 
 // Slottype represents the type of a board slot (property, card, jail, etc.).
@@ -51,8 +31,11 @@ type Slottype string
 
 const (
 	SlotTypeProperty Slottype = "property"
+	SlotTypeRailroad Slottype = "railroad"
+	SlotTypeUtility  Slottype = "utility"
 	SlotTypeCard     Slottype = "card"
 	SlotTypeJail     Slottype = "jail"
+	SlotTypeGoToJail Slottype = "go_to_jail"
 	SlotTypeTax      Slottype = "tax"
 	SlotTypeNeutral  Slottype = "neutral"
 )
@@ -63,53 +46,67 @@ type Slot struct {
 	Type  Slottype
 	Owner IdType
 	Price int
-	State int
-	Rent1 int
-	Rent2 int
-	Rent3 int
-	Rent4 int
-	Rent5 int
+	// Group is the color group of a property, building needs the whole group
+	Group string
+	// Deck is the deck a card slot draws from
+	Deck string
+	// Kind tells neutral slots such as Go and Free Parking apart
+	Kind NeutralKind
+	// TaxMode and TaxPercent configure tax slots, the flat tax is the Price
+	TaxMode    TaxMode
+	TaxPercent int
+	// Mortgaged properties collect no rent until the mortgage is lifted
+	Mortgaged bool
+	State     int
+	Rent1     int
+	Rent2     int
+	Rent3     int
+	Rent4     int
+	Rent5     int
 	// appends slot here
 	// Slot is a slot
 	// slot is kind of like parent class
 }
 
-// RemovePlayer removes a player from the game and resets their properties.
+// RemovePlayer removes a player who forfeits the game.
+// Forfeiting is a bankruptcy: assets go to the player they owe, or back to the bank for auction.
 func (b *Board) RemovePlayer(player *Player) (string, error) {
-	b.Lock()
-	defer b.Unlock()
+	if b.GetPlayer(player.Id) == nil {
+		return "", fmt.Errorf("player not found")
+	}
+	msg, _, err := b.DeclareBankruptcy(player)
+	return msg, err
+}
 
+// removePlayer takes the player out of the turn order.
+// It must be called with the board locked.
+func (b *Board) removePlayer(player *Player) {
 	// Find the index of the player to remove
-	var index int
-	var found bool
+	index := -1
 	for i, p := range b.Players {
 		if p.Id == player.Id {
 			index = i
-			found = true
 			break
 		}
 	}
-
-	if !found {
-		return "", fmt.Errorf("player not found")
+	if index == -1 {
+		return
 	}
 
 	// Remove the player from the Players slice
 	b.Players = append(b.Players[:index], b.Players[index+1:]...)
 
-	// Handle properties owned by the player
-	for i := range b.Slots {
-		if b.Slots[i].Owner == player.Id {
-			b.Slots[i].Owner = nil
-		}
+	// Adjust the turn if necessary, once the game is on the next player inherits a fresh turn
+	currentLeft := index == b.Turn && b.started
+	if index < b.Turn {
+		b.Turn--
 	}
-
-	// Adjust the turn if necessary
 	if b.Turn >= len(b.Players) {
 		b.Turn = 0
 	}
-
-	return fmt.Sprintf("%s has been removed from the game", player.Name), nil
+	if currentLeft {
+		b.startTurn()
+	}
 }
 
 // Player represents a player in the game.
@@ -125,19 +122,64 @@ type Player struct {
 
 // Board holds the state of the game, including players, slots, trades, and turn management.
 type Board struct {
-	Slots        []Slot
-	Cards        []Card
-	Players      []*Player
-	Trades       []*GameTradeBody
+	Slots []Slot
+	// Cards holds every card in the game, indexed by card id
+	Cards []*Card
+	// Decks are the draw and discard piles by deck name
+	Decks   map[string]*Deck
+	Players []*Player
+	// Trades holds the open trades by their server assigned id
+	Trades       map[int]*GameTradeBody
 	TradeHistory []TradeHistoryEntry
 	Turn         int
 	sync.Mutex
-	// Turn Done to prevent player from ending turn without completing Turn Duties
-	// After completing a set of actions it unlocks and current players turn can end
-	// But still Go/ Move is locekd
-	TurnDone bool
-	// Move lock to prevent current player to Go multiple times
-	MoveLock bool
+	// Phase is where the current turn stands, it decides which actions are legal
+	Phase TurnPhase
+	// resumePhase is the phase to return to once an auction or debt is resolved
+	resumePhase TurnPhase
+	// started is set by StartGame, there are no turns in the lobby before it
+	started bool
+	// LastRoll is the most recent roll of the dice
+	LastRoll DiceRoll
+	// Doubles counts consecutive doubles rolled by the current player this turn
+	Doubles int
+	// Rules are the house rules this game is played with
+	Rules Rules
+	// HouseCosts is the price of one house (or hotel) per color group
+	HouseCosts map[string]int
+	// Houses and Hotels are the buildings the bank has left to sell
+	Houses int
+	Hotels int
+	// PendingPurchase is the unowned slot the current player landed on and has not bought or declined yet
+	PendingPurchase IdType
+	// PendingTax is the tax slot the current player landed on and has not chosen how to pay yet
+	PendingTax IdType
+	// PendingPercentTax is what the percent option of the pending tax comes to, valued on landing
+	PendingPercentTax int
+	// Auction is the auction in progress, if any
+	Auction *Auction
+	// AuctionQueue holds slots waiting to be auctioned after the current auction, such as a bankrupt player's properties
+	AuctionQueue []int
+	// Debts are payments players could not make, they block the end of the turn until paid
+	Debts []*Debt
+	// Jackpot is the Free Parking pot of taxes and fines, under the house rule
+	Jackpot int
+	// Contracts are the traded rights in force, until their laps run out
+	Contracts []*Contract
+	// Loans are the loans between players by id, proposed, running or settled
+	Loans map[int]*Loan
+	// TurnNumber counts the turns played, loans fall due by it
+	TurnNumber int
+
+	nextPlayerId   int
+	nextTradeId    int
+	nextContractId int
+	nextLoanId     int
+	// events wait to be delivered by the room, see DrainEvents
+	events []Event
+	// state is the last snapshot sent to clients, at stateVersion
+	state        *Snapshot
+	stateVersion int
 }
 
 type IdType *int
@@ -145,116 +187,195 @@ type IdType *int
 type (
 	// GameTradeBody represents a trade proposal between two players.
 	GameTradeBody struct {
-		Requester IdType       `json:"requster" validate:"required"`
-		Id        IdType       `json:"responder" validate:"required"` // `required` ensures this field must be present
-		Responder IdType       `json:"from" validate:"required"`
+		// Id and Requester are filled in by the server when the trade is offered
+		Requester IdType       `json:"requester"`
+		Id        IdType       `json:"id"`
+		Responder IdType       `json:"responder" validate:"required"` // `required` ensures this field must be present
 		Give      TradeDetails `json:"give,omitempty"`
 		Take      TradeDetails `json:"take,omitempty"`
 		Accept    bool         `json:"accept,omitempty"`
 		Active    bool         `json:"active,omitempty"`
-	}
-	// GameTradeAcceptBody represents a request to accept a trade.
+		// Counters is the open trade this one answers as a counter-offer
+		Counters IdType      `json:"counters,omitempty"`
+		Status   TradeStatus `json:"status,omitempty"`
+		// Expires is when an open trade lapses if it has not been answered
+		Expires time.Time `json:"expires,omitempty"`
+		// Legs are further transfers between any players in the game, for deals between three or more
+		Legs []TradeLeg `json:"legs,omitempty"`
+		// Accepted lists the players who accepted so far, the trade goes through once every party has
+		Accepted []IdType `json:"accepted,omitempty"`
+	}
+	// GameTradeAcceptBody names a trade to accept, reject or cancel.
 	GameTradeAcceptBody struct {
 		TradeId IdType `json:"tradeId" validate:"required"`
 	}
 )
 
-// TradeDetails describes the assets involved in a trade (properties, money, cards).
+// TradeDetails describes the assets involved in a trade (properties, money, cards, future rights).
 type TradeDetails struct {
-	Property []IdType `json:"property,omitempty"`
-	Money    int      `json:"money,omitempty"`
-	Cards    []IdType `json:"cards,omitempty"`
+	Property []IdType     `json:"property,omitempty"`
+	Money    int          `json:"money,omitempty"`
+	Cards    []IdType     `json:"cards,omitempty"`
+	Rights   []TradeRight `json:"rights,omitempty"`
 }
 
 // Card represents a special card with an effect in the game.
 type Card struct {
+	Id          IdType
 	Name        string
 	Description string
-	Effect      func(*Player, *Board) (string, error)
+	Deck        string
+	// Keep cards go to the drawing player's inventory to be played later instead of taking effect
+	Keep bool
+	// CanPlay reports whether a kept card may be played right now, nil means any time on the player's turn
+	CanPlay func(*Player, *Board) bool
+	Effect  CardEffect
 }
 
 // LOOC YREV
 // ----------
+// NewBoard creates the built-in board. Other boards are loaded from definition files with LoadBoard.
 func NewBoard() *Board {
 	// Create a simple board with properties
 
 	slots := []Slot{
-		{Name: "Mediterranean Avenue", Type: SlotTypeProperty, Owner: nil, Price: 60, State: 0},
-		{Name: "Arkochan Avenue", Type: SlotTypeProperty, Owner: nil, Price: 60, State: 0},
-		{Name: "Chittagong", Type: SlotTypeProperty, Owner: nil, Price: 60, State: 0},
-		// {Name: "Community Chest", Type: SlotTypeCard, Owner: nil, Price: 0, Houses: 0},
-		{Name: "Hell Yeah Avenue", Type: SlotTypeProperty, Owner: nil, Price: 60, State: 0},
-		{Name: "Nicsu York", Type: SlotTypeProperty, Owner: nil, Price: 60, State: 0},
-		{Name: "MiniSoda", Type: SlotTypeProperty, Owner: nil, Price: 60, State: 0},
-		{Name: "Ohio", Type: SlotTypeProperty, Owner: nil, Price: 60, State: 0},
+		{Name: "Go", Type: SlotTypeNeutral, Owner: nil, Price: 0, State: 0, Kind: NeutralKindGo},
+		{Name: "Mediterranean Avenue", Type: SlotTypeProperty, Owner: nil, Price: 60, Group: "brown", State: 0, Rent1: 2, Rent2: 10, Rent3: 30, Rent4: 90, Rent5: 250},
+		{Name: "Community Chest", Type: SlotTypeCard, Owner: nil, Price: 0, Deck: "community_chest"},
+		{Name: "Arkochan Avenue", Type: SlotTypeProperty, Owner: nil, Price: 60, Group: "brown", State: 0, Rent1: 4, Rent2: 20, Rent3: 60, Rent4: 180, Rent5: 450},
+		{Name: "Kamalapur Station", Type: SlotTypeRailroad, Owner: nil, Price: 200, State: 0, Rent1: 25, Rent2: 50, Rent3: 100, Rent4: 200},
+		{Name: "Income Tax", Type: SlotTypeTax, Owner: nil, Price: 200, TaxMode: TaxModeChoice, TaxPercent: 10},
+		{Name: "Chittagong", Type: SlotTypeProperty, Owner: nil, Price: 100, Group: "light_blue", State: 0, Rent1: 6, Rent2: 30, Rent3: 90, Rent4: 270, Rent5: 550},
+		{Name: "Chance", Type: SlotTypeCard, Owner: nil, Price: 0, Deck: "chance"},
+		{Name: "Hell Yeah Avenue", Type: SlotTypeProperty, Owner: nil, Price: 100, Group: "light_blue", State: 0, Rent1: 6, Rent2: 30, Rent3: 90, Rent4: 270, Rent5: 550},
+		{Name: "Nicsu York", Type: SlotTypeProperty, Owner: nil, Price: 120, Group: "light_blue", State: 0, Rent1: 8, Rent2: 40, Rent3: 100, Rent4: 300, Rent5: 600},
+		{Name: "Jail", Type: SlotTypeJail, Owner: nil, Price: 0},
+		{Name: "Electric Company", Type: SlotTypeUtility, Owner: nil, Price: 150, State: 0, Rent1: 4, Rent2: 10},
+		{Name: "Free Parking", Type: SlotTypeNeutral, Owner: nil, Price: 0, Kind: NeutralKindFreeParking},
+		{Name: "MiniSoda", Type: SlotTypeProperty, Owner: nil, Price: 140, Group: "pink", State: 0, Rent1: 10, Rent2: 50, Rent3: 150, Rent4: 450, Rent5: 750},
+		{Name: "Ohio", Type: SlotTypeProperty, Owner: nil, Price: 160, Group: "pink", State: 0, Rent1: 12, Rent2: 60, Rent3: 180, Rent4: 500, Rent5: 900},
+		{Name: "Airport Station", Type: SlotTypeRailroad, Owner: nil, Price: 200, State: 0, Rent1: 25, Rent2: 50, Rent3: 100, Rent4: 200},
+		{Name: "Go To Jail", Type: SlotTypeGoToJail, Owner: nil, Price: 0},
+		{Name: "Water Works", Type: SlotTypeUtility, Owner: nil, Price: 150, State: 0, Rent1: 4, Rent2: 10},
+	}
+	houseCosts := map[string]int{
+		"brown":      50,
+		"light_blue": 50,
+		"pink":       100,
+	}
+	position := func(name string) int {
+		for i, slot := range slots {
+			if slot.Name == name {
+				return i
+			}
+		}
+		return 0
 	}
 	cards := []Card{
-		{Name: "Jail Free Card", Description: "Get out of jail free card", Effect: func(p *Player, b *Board) (string, error) {
-			p.InJail = false
-			return fmt.Sprintf("%s used a Jail Free Card", p.Name), nil
-		}},
-		{Name: "Advance to Go", Description: "Advance to Go and collect $200", Effect: func(p *Player, b *Board) (string, error) {
-			p.Position = 0
-			b.TransferBankToPlayer(p, 200)
-			return fmt.Sprintf("%s advanced to Go and collected $200", p.Name), nil
-		}},
-	}
-	return &Board{
+		{Name: "Jail Free Card", Description: "Get out of jail free card", Deck: "chance", Keep: true, CanPlay: InJail, Effect: JailFreeEffect()},
+		{Name: "Advance to Go", Description: "Advance to Go and collect your salary", Deck: "chance", Effect: AdvanceToEffect(0)},
+		{Name: "Advance to Ohio", Description: "Advance to Ohio, if you pass Go collect your salary", Deck: "chance", Effect: AdvanceToEffect(position("Ohio"))},
+		{Name: "Go Back", Description: "Go back 3 spaces", Deck: "chance", Effect: MoveBackEffect(3)},
+		{Name: "General Repairs", Description: "Pay 25 for each house and 100 for each hotel", Deck: "chance", Effect: PayPerBuildingEffect(25, 100)},
+		{Name: "Go to Jail", Description: "Go directly to jail, do not pass Go", Deck: "chance", Effect: GoToJailEffect()},
+		{Name: "Dividend", Description: "Bank pays you a dividend of 50", Deck: "chance", Effect: CollectEffect(50)},
+		{Name: "Jail Free Card", Description: "Get out of jail free card", Deck: "community_chest", Keep: true, CanPlay: InJail, Effect: JailFreeEffect()},
+		{Name: "Advance to Go", Description: "Advance to Go and collect your salary", Deck: "community_chest", Effect: AdvanceToEffect(0)},
+		{Name: "Birthday", Description: "It is your birthday, collect 10 from every player", Deck: "community_chest", Effect: CollectFromEachPlayerEffect(10)},
+		{Name: "Doctor's Fee", Description: "Pay 50 in doctor's fees", Deck: "community_chest", Effect: PayEffect(50)},
+		{Name: "Street Repairs", Description: "Pay 40 for each house and 115 for each hotel", Deck: "community_chest", Effect: PayPerBuildingEffect(40, 115)},
+		{Name: "Go to Jail", Description: "Go directly to jail, do not pass Go", Deck: "community_chest", Effect: GoToJailEffect()},
+		{Name: "Bank Error", Description: "Bank error in your favor, collect 200", Deck: "community_chest", Effect: CollectEffect(200)},
+	}
+	return newBoard(slots, houseCosts, cards)
+}
+
+// newBoard sets up a board with fresh bank supplies and shuffled decks.
+func newBoard(slots []Slot, houseCosts map[string]int, cards []Card) *Board {
+	b := &Board{
 		Slots:   slots,
-		Cards:   cards,
 		Players: []*Player{},
+		Trades:  map[int]*GameTradeBody{},
+		Loans:   map[int]*Loan{},
 		Turn:    0,
+		Phase:   PhasePreRoll,
+
+		HouseCosts: houseCosts,
 	}
+	b.ApplyRules(DefaultRules())
+	for _, card := range cards {
+		b.AddCard(card)
+	}
+	b.ShuffleDecks()
+	return b
 }
 
 // AddPlayer adds a new player to the board and returns the player instance.
 func (b *Board) AddPlayer(name string) *Player {
-	newId := len(b.Players)
-	player := &Player{Name: name, Money: 1500, Position: 0, Id: &newId}
 	b.Lock()
+	newId := b.nextPlayerId
+	b.nextPlayerId++
+	player := &Player{Name: name, Money: b.Rules.StartingMoney, Position: 0, Id: &newId}
 	b.Players = append(b.Players, player)
+	b.commitState()
 	b.Unlock()
 	return player
 }
 
-// CurrentPlayer returns the player whose turn it is.
-func (b *Board) CurrentPlayer() *Player {
+// DropPlayer takes a player out before the game has started, when they have nothing to hand over.
+func (b *Board) DropPlayer(player *Player) {
 	b.Lock()
 	defer b.Unlock()
-	return b.Players[b.Turn]
+	b.removePlayer(player)
+	b.commitState()
 }
 
-// NextTurn advances the turn to the next player.
-func (b *Board) NextTurn() {
+// StartGame shuffles the turn order and opens the first turn.
+// It returns the events to deliver, ending with the turn order.
+func (b *Board) StartGame() []Event {
 	b.Lock()
-	b.Turn = (b.Turn + 1) % len(b.Players)
-	b.Unlock()
-}
+	defer b.Unlock()
+	rand.Shuffle(len(b.Players), func(i, j int) {
+		b.Players[i], b.Players[j] = b.Players[j], b.Players[i]
+	})
+	b.Turn = 0
+	b.TurnNumber = 0
+	b.started = true
+	b.startTurn()
+	b.commitState()
 
-// lock and unlock player movelock property
-func (b *Board) LockPlayerMove(player *Player) {
-	b.Lock()
-	b.MoveLock = true
-	b.Unlock()
+	names := []string{}
+	for _, player := range b.Players {
+		names = append(names, player.Name)
+	}
+	events := b.drainEvents()
+	return append(events, TextEvent(EventMessage, nil, "The game has started, turn order: "+strings.Join(names, ", ")))
 }
 
-func (b *Board) UnlockPlayerMove(player *Player) {
+// CurrentPlayer returns the player whose turn it is.
+func (b *Board) CurrentPlayer() *Player {
 	b.Lock()
-	b.MoveLock = false
-	b.Unlock()
+	defer b.Unlock()
+	return b.Players[b.Turn]
 }
 
-// lock and unlock TurnDone property
-func (b *Board) LockTurnDone() {
+// NextTurn advances the turn to the next player, collecting the loans that fall due.
+// It returns a message to broadcast, if any loan was settled.
+func (b *Board) NextTurn() string {
 	b.Lock()
-	b.TurnDone = true
-	b.Unlock()
+	defer b.Unlock()
+	return b.nextTurn()
 }
 
-func (b *Board) UnlockTurnDone() {
-	b.Lock()
-	b.TurnDone = false
-	b.Unlock()
+// nextTurn is NextTurn for callers that already hold the board lock.
+func (b *Board) nextTurn() string {
+	b.Turn = (b.Turn + 1) % len(b.Players)
+	b.TurnNumber++
+	msg := b.collectLoans()
+	b.startTurn()
+	// A defaulted loan may have left a debt to settle before the turn is played
+	b.refreshPhase()
+	return msg
 }
 
 func (b *Board) PlayerCount() int {
@@ -296,86 +417,114 @@ func (b *Board) TransferPlayerToBank(sender *Player, amount int) error {
 	return nil
 }
 
-func (b *Board) TransferPlayerToPlayer(sender *Player, receiver *Player, amount int) (func(), error) {
+func (b *Board) TransferPlayerToPlayer(sender *Player, receiver *Player, amount int) error {
 	if sender.Money < amount {
-		return nil, fmt.Errorf("insufficient funds")
+		return fmt.Errorf("insufficient funds")
 	}
 	sender.Money -= amount
 	receiver.Money += amount
-	return func() { b.TransferPlayerToPlayer(receiver, sender, amount) }, nil
+	return nil
 }
 
-func (b *Board) RollDice() int {
-	return rand.Intn(12) + 1
+// RollDice rolls two six-sided dice and remembers the result on the board.
+func (b *Board) RollDice() DiceRoll {
+	roll := DiceRoll{Die1: rand.Intn(6) + 1, Die2: rand.Intn(6) + 1}
+	b.LastRoll = roll
+	return roll
 }
 
 // Transfer Properties
+// The receiver of a mortgaged property pays the bank the mortgage interest on it straight away.
 func (b *Board) TransferProperty(sender *Player, receiver *Player, properties ...IdType) error {
 	b.Lock()
 	defer b.Unlock()
+	return b.transact(Transaction{From: sender, To: receiver, Properties: properties}, b.interestTransaction(receiver, properties))
+}
+
+// interestTransaction charges the receiver of properties the interest on the mortgaged ones.
+// It must be called with the board locked.
+func (b *Board) interestTransaction(receiver *Player, properties []IdType) Transaction {
+	interest := 0
 	for _, property := range properties {
-		// PROBLEM: This will create problem as a serch function will be needed find out propertie's board position.
-		if b.Slots[*property].Owner != sender.Id {
-			return fmt.Errorf("not owner of property")
+		if slot, err := b.slotAt(property); err == nil && slot.Mortgaged {
+			interest += slot.mortgageInterest()
 		}
 	}
-	for _, property := range properties {
-		b.Slots[*property].Owner = receiver.Id
-	}
-	return nil
+	return Transaction{From: receiver, Money: interest}
 }
 
 // HandleTradeAccept processes a trade acceptance between players.
+// The trade goes through once every party other than the requester has accepted.
+// Money, properties, cards and mortgage interest all move in one transaction, or nothing moves,
+// and the traded rights come into force with it.
+// It must be called with the board locked.
 func (b *Board) HandleTradeAccept(player *Player, tradeAcceptBody GameTradeAcceptBody) (string, string, error) {
-	trade := b.Trades[*tradeAcceptBody.TradeId]
-	requester := b.GetPlayer(trade.Requester)
-	responder := b.GetPlayer(trade.Responder)
-
-	if player.Id != responder.Id {
-		return "", "", fmt.Errorf("not your trade")
+	trade := b.tradeById(tradeAcceptBody.TradeId)
+	if trade == nil {
+		return "", "", fmt.Errorf("no open trade with that id")
 	}
-	var reverts []func()
-	revert, err := b.TransferPlayerToPlayer(requester, responder, trade.Give.Money)
-	if err != nil {
-		return "", "", err
+	if *player.Id == *trade.Requester || !trade.involves(player) {
+		return "", "", fmt.Errorf("not your trade")
 	}
-
-	reverts = append(reverts, revert)
-
-	_, err = b.TransferPlayerToPlayer(responder, requester, trade.Take.Money)
-	if err != nil {
-		for _, r := range reverts {
-			r()
-		}
-		return "", "", err
+	if containsId(trade.Accepted, player.Id) {
+		return "", "", fmt.Errorf("already accepted")
 	}
-
-	// 2. Transfer Property
-	if err := b.TransferProperty(requester, responder, trade.Give.Property...); err != nil {
+	if err := b.checkTradeAssets(trade); err != nil {
 		return "", "", err
 	}
 
-	if err := b.TransferProperty(responder, requester, trade.Take.Property...); err != nil {
-		return "", "", err
+	if pending := trade.pendingParties(); len(pending) > 1 {
+		trade.Accepted = append(trade.Accepted, player.Id)
+		names := []string{}
+		for _, id := range pending {
+			if *id != *player.Id {
+				names = append(names, b.playerName(id))
+			}
+		}
+		b.notifyParties(trade, player, "%s accepted trade %d", player.Name, *trade.Id)
+		return fmt.Sprintf("%s accepted trade %d, waiting for %s", player.Name, *trade.Id, strings.Join(names, ", ")), "", nil
 	}
 
-	// Transfer Cards
-	if err := b.TransferCards(requester, responder, trade.Give.Cards...); err != nil {
+	if err := b.transact(b.tradeTransactions(trade)...); err != nil {
 		return "", "", err
 	}
-
-	if err := b.TransferCards(responder, requester, trade.Take.Cards...); err != nil {
-		return "", "", err
+	trade.Accepted = append(trade.Accepted, player.Id)
+	for _, leg := range trade.legs() {
+		for _, right := range leg.Details.Rights {
+			b.grantRight(b.GetPlayer(leg.From), b.GetPlayer(leg.To), right)
+		}
 	}
 
-	return "", "", nil
+	b.resolveTrade(trade, TradeStatusAccepted)
+	b.notifyParties(trade, player, "%s accepted trade %d, it went through", player.Name, *trade.Id)
+	return fmt.Sprintf("%s accepted trade %d: %s", player.Name, *trade.Id, b.describeTrade(trade)), "", nil
 }
 
 // HandleAction processes a game action from a player.
 // This version does NOT depend on room.Message or room.Action* constants.
-// Instead, it takes a generic action string and a body (payload), and returns messages/errors.
-func (b *Board) HandleAction(player *Player, action string, body interface{}) (string, string, error) {
+// Instead, it takes a generic action string and a body (payload), and returns
+// the events the action caused, ending with its messages or error.
+// The whole action runs with the board locked, from the phase check to the events.
+func (b *Board) HandleAction(player *Player, action string, body interface{}) []Event {
+	b.Lock()
+	defer b.Unlock()
+
 	// The room package is responsible for interpreting the action string and body.
+	if b.GetPlayer(player.Id) == nil {
+		return []Event{TextEvent(EventError, player.Id, "you are no longer in the game")}
+	}
+	if !b.isAllowed(player, action) {
+		return []Event{TextEvent(EventError, player.Id, fmt.Sprintf("%s is not allowed now (%s)", action, b.Phase))}
+	}
+	msg, prompt, err := b.dispatchAction(player, action, body)
+
+	// Whatever changed hands may have broken open trades
+	return b.actionEvents(player, joinMessages(msg, b.invalidateTrades()), prompt, err)
+}
+
+// dispatchAction hands an allowed action to its handler.
+// It must be called with the board locked, the handlers do not take the lock themselves.
+func (b *Board) dispatchAction(player *Player, action string, body interface{}) (string, string, error) {
 	switch action {
 	case "trade":
 		tradeBody, ok := body.(GameTradeBody)
@@ -389,170 +538,312 @@ func (b *Board) HandleAction(player *Player, action string, body interface{}) (s
 			return "", "", fmt.Errorf("invalid accept trade body")
 		}
 		return b.HandleTradeAccept(player, acceptBody)
+	case "reject_trade":
+		rejectBody, ok := body.(GameTradeAcceptBody)
+		if !ok {
+			return "", "", fmt.Errorf("invalid reject trade body")
+		}
+		return b.RejectTrade(player, rejectBody)
+	case "cancel_trade":
+		cancelBody, ok := body.(GameTradeAcceptBody)
+		if !ok {
+			return "", "", fmt.Errorf("invalid cancel trade body")
+		}
+		return b.CancelTrade(player, cancelBody)
+	case "counter_trade":
+		counterBody, ok := body.(GameTradeBody)
+		if !ok {
+			return "", "", fmt.Errorf("invalid counter trade body")
+		}
+		return b.CounterTrade(player, counterBody)
+	case "propose_loan":
+		loanBody, ok := body.(GameLoanBody)
+		if !ok {
+			return "", "", fmt.Errorf("invalid loan body")
+		}
+		return b.ProposeLoan(player, loanBody)
+	case "accept_loan":
+		loanIdBody, ok := body.(GameLoanIdBody)
+		if !ok {
+			return "", "", fmt.Errorf("invalid loan body")
+		}
+		return b.AcceptLoan(player, loanIdBody)
+	case "reject_loan":
+		loanIdBody, ok := body.(GameLoanIdBody)
+		if !ok {
+			return "", "", fmt.Errorf("invalid loan body")
+		}
+		return b.RejectLoan(player, loanIdBody)
+	case "bid":
+		bidBody, ok := body.(GameBidBody)
+		if !ok {
+			return "", "", fmt.Errorf("invalid bid body")
+		}
+		return b.Bid(player, bidBody)
+	case "sell_house":
+		propertyBody, ok := body.(GamePropertyBody)
+		if !ok {
+			return "", "", fmt.Errorf("invalid house body")
+		}
+		return b.SellHouse(player, propertyBody)
+	case "mortgage":
+		propertyBody, ok := body.(GamePropertyBody)
+		if !ok {
+			return "", "", fmt.Errorf("invalid mortgage body")
+		}
+		return b.MortgageProperty(player, propertyBody)
+	case "pay_debt":
+		return b.PayDebt(player)
+	case "bankrupt":
+		return b.DeclareBankruptcy(player)
 	case "forfeit_game":
 		msg, err := b.RemovePlayer(player)
 		if err != nil {
 			return "", "", err
 		}
 		return msg, "", nil
-	default:
-		// Only allow certain actions if it's the player's turn
-		if player.Name == b.CurrentPlayer().Name {
-			b.LockTurnDone()
-			switch action {
-			case "go":
-				return b.HandleGo(player)
-			case "buy":
-				return b.BuyProperty(player)
-			case "end_turn":
-				return b.HandleEndTurn(player)
-				// Add more actions as needed
-			}
+	case "go":
+		return b.HandleGo(player)
+	case "buy":
+		return b.BuyProperty(player)
+	case "decline":
+		return b.DeclinePurchase(player)
+	case "pay_tax":
+		taxBody, ok := body.(GameTaxBody)
+		if !ok {
+			return "", "", fmt.Errorf("invalid tax body")
+		}
+		return b.PayTax(player, taxBody)
+	case "pay_fine":
+		return b.PayJailFine(player)
+	case "use_card":
+		useCardBody, ok := body.(GameUseCardBody)
+		if !ok {
+			return "", "", fmt.Errorf("invalid use card body")
 		}
+		return b.UseCard(player, useCardBody)
+	case "build_house":
+		propertyBody, ok := body.(GamePropertyBody)
+		if !ok {
+			return "", "", fmt.Errorf("invalid house body")
+		}
+		return b.BuildHouse(player, propertyBody)
+	case "unmortgage":
+		propertyBody, ok := body.(GamePropertyBody)
+		if !ok {
+			return "", "", fmt.Errorf("invalid mortgage body")
+		}
+		return b.UnmortgageProperty(player, propertyBody)
+	case "end_turn":
+		return b.HandleEndTurn(player)
 	}
 	return "", "", fmt.Errorf("error invalid action")
 }
 
-func (b *Board) GetPlayer(id IdType) *Player {
-	return b.Players[*id]
+// LookupPlayer is GetPlayer for callers outside the game, it takes the board lock.
+func (b *Board) LookupPlayer(id IdType) *Player {
+	b.Lock()
+	defer b.Unlock()
+	return b.GetPlayer(id)
 }
 
-// Check if trade body is valid
-func (b *Board) CheckTradeBody(tradeBody GameTradeBody) error {
-	if tradeBody.Id == nil {
-		return fmt.Errorf("missing trade id")
+// GetPlayer finds a player by id, returning nil if they are not (or no longer) in the game.
+// It does not take the board lock.
+func (b *Board) GetPlayer(id IdType) *Player {
+	if id == nil {
+		return nil
 	}
-	if tradeBody.Requester == nil || tradeBody.Responder == nil {
-		return fmt.Errorf("missing trade participant")
+	for _, p := range b.Players {
+		if *p.Id == *id {
+			return p
+		}
 	}
 	return nil
 }
 
-// check if player is the owner
-func (b *Board) isOwner(player *Player, propertyIds ...IdType) bool {
-	for _, i := range propertyIds {
-		if b.Slots[*i].Owner != player.Id {
-			return false
+// Check if trade body is valid
+func (b *Board) CheckTradeBody(tradeBody GameTradeBody) error {
+	// The requester is whoever sends the trade, only the other parties have to be named
+	if tradeBody.Responder == nil && len(tradeBody.Legs) == 0 {
+		return fmt.Errorf("missing trade participant")
+	}
+	for _, leg := range tradeBody.Legs {
+		if leg.From == nil || leg.To == nil {
+			return fmt.Errorf("missing trade participant")
+		}
+	}
+	for _, leg := range tradeBody.legs() {
+		for _, id := range append(append([]IdType{}, leg.Details.Property...), leg.Details.Cards...) {
+			if id == nil {
+				return fmt.Errorf("missing property or card id")
+			}
 		}
 	}
-	return true
+	return nil
 }
 
 // Trade Details checker
-func (b *Board) CheckTradeDetails(from *Player, tradeBody GameTradeBody) error {
-	to := b.GetPlayer(tradeBody.Requester)
-	fmt.Println("name" + to.Name)
-
-	// check if from id is same
-	if from.Id != tradeBody.Responder {
-		fmt.Println("from id is not same")
-	}
-
-	if tradeBody.Give.Money > from.Money || tradeBody.Take.Money > to.Money {
-		return fmt.Errorf("insufficient funds")
-	}
-
-	if tradeBody.Give.Money < 0 || tradeBody.Take.Money < 0 {
-		return fmt.Errorf("invalid amount")
-	}
-
-	if !b.isOwner(from, tradeBody.Give.Property...) {
-		return fmt.Errorf("not owner of property")
-	}
-	if !b.isOwner(to, tradeBody.Take.Property...) {
-		return fmt.Errorf("not owner of property")
+// The player offering the trade is always its requester, whatever the body says.
+func (b *Board) CheckTradeDetails(from *Player, tradeBody *GameTradeBody) error {
+	tradeBody.Requester = from.Id
+	tradeBody.Accepted = nil
+	for _, leg := range tradeBody.legs() {
+		sender := b.GetPlayer(leg.From)
+		if sender == nil || b.GetPlayer(leg.To) == nil {
+			return fmt.Errorf("player not found")
+		}
+		if leg.Details.Money < 0 {
+			return fmt.Errorf("invalid amount")
+		}
+		if leg.Details.Money > sender.Money {
+			return fmt.Errorf("insufficient funds")
+		}
 	}
-
-	return nil
+	return b.checkTradeAssets(tradeBody)
 }
 
 // Enlist Trade
-func (b *Board) EnlistTrade(tradeBody GameTradeBody) {
+// The trade gets a fresh id and stays open until it is accepted, rejected, cancelled, countered or it expires.
+func (b *Board) EnlistTrade(tradeBody GameTradeBody) *GameTradeBody {
 	b.Lock()
 	defer b.Unlock()
+	return b.enlistTrade(tradeBody)
+}
 
-	// Add the trade to the list of trades
-	b.Trades = append(b.Trades, &tradeBody)
-
-	// Create a new trade history entry
-	tradeHistoryEntry := TradeHistoryEntry{
-		RequesterName: b.GetPlayer(tradeBody.Requester).Name,
-		ResponderName: b.GetPlayer(tradeBody.Responder).Name,
-		Give:          tradeBody.Give,
-		Take:          tradeBody.Take,
-		Timestamp:     time.Now().Format(time.RFC3339),
-	}
-
-	// Add the trade history entry to the trade history
-	b.TradeHistory = append(b.TradeHistory, tradeHistoryEntry)
+// enlistTrade is EnlistTrade for callers that already hold the board lock.
+func (b *Board) enlistTrade(tradeBody GameTradeBody) *GameTradeBody {
+	b.nextTradeId++
+	id := b.nextTradeId
+	tradeBody.Id = &id
+	tradeBody.Active = true
+	tradeBody.Accept = false
+	tradeBody.Status = TradeStatusOpen
+	tradeBody.Expires = time.Now().Add(b.Rules.TradeDuration)
+	b.Trades[id] = &tradeBody
+	return &tradeBody
 }
 
 func (b *Board) HandleTrade(from *Player, tradeBody GameTradeBody) (string, string, error) {
+	return b.proposeTrade(from, tradeBody, nil)
+}
+
+// proposeTrade opens a new trade, replacing the original trade when it is a counter-offer.
+// It must be called with the board locked.
+func (b *Board) proposeTrade(from *Player, tradeBody GameTradeBody, original *GameTradeBody) (string, string, error) {
+	tradeBody.Counters = nil
+	if original != nil {
+		tradeBody.Counters = original.Id
+	}
 	err := b.CheckTradeBody(tradeBody)
 	if err != nil {
 		return "", "", err
 	}
-	err = b.CheckTradeDetails(from, tradeBody)
+	err = b.CheckTradeDetails(from, &tradeBody)
 	if err != nil {
 		return "", "", err
 	}
 
-	b.EnlistTrade(tradeBody)
-	return fmt.Sprintf("New trade Added, Id: %d", tradeBody.Id), "", nil
+	trade := b.enlistTrade(tradeBody)
+	msg := fmt.Sprintf("%s offered trade %d: %s", from.Name, *trade.Id, b.describeTrade(trade))
+	if original != nil && original.Active {
+		b.resolveTrade(original, TradeStatusCountered)
+		msg = fmt.Sprintf("%s countered trade %d with trade %d: %s", from.Name, *original.Id, *trade.Id, b.describeTrade(trade))
+	}
+	b.emit(EventTradeProposed, from, *trade)
+	b.notifyParties(trade, from, "%s offers you trade %d: %s. Accept, reject or counter it within %s",
+		from.Name, *trade.Id, b.describeTrade(trade), b.Rules.TradeDuration)
+	return msg, fmt.Sprintf("Your trade has id %d", *trade.Id), nil
 }
 
 // BuyProperty allows a player to purchase the property they are currently on.
 func (b *Board) BuyProperty(player *Player) (string, string, error) {
-	slot := b.Slots[player.Position]
+	slot := &b.Slots[player.Position]
+	if !slot.IsOwnable() {
+		return "", "", fmt.Errorf("%s cannot be bought", slot.Name)
+	}
 	if slot.Owner != nil {
 		return "", "", fmt.Errorf("slot already owned")
 	}
 	if player.Money < slot.Price {
 		return "", "", fmt.Errorf("insufficient funds")
 	}
-	b.TransferPlayerToBank(player, slot.Price)
-	slot.Owner = player.Id
+	position := player.Position
+	err := b.transact(Transaction{From: player, Money: slot.Price}, Transaction{To: player, Properties: []IdType{&position}})
+	if err != nil {
+		return "", "", err
+	}
+	b.PendingPurchase = nil
+	b.emit(EventPropertyBought, player, PropertyPayload{Property: position, Price: slot.Price})
+	b.settle(b.afterRollPhase())
 	return fmt.Sprintf("%s bought %s for %d", player.Name, slot.Name, slot.Price), "", nil
 }
 
-// function to calculate rent
-func (b *Board) calculateRent(currentSlot Slot) (int, error) {
-	if currentSlot.Owner == nil {
-		return 0, nil
+// MovePlayer moves the player forward (or back, for negative steps) and resolves the slot they land on.
+// Moving forward past or onto Go pays the salary.
+func (b *Board) MovePlayer(player *Player, steps int) (string, string, error) {
+	salaryMsg, salary := "", 0
+	passedGo := steps > 0 && player.Position+steps >= len(b.Slots)
+	if passedGo {
+		landed := (player.Position+steps)%len(b.Slots) == 0
+		salary = b.goSalary(landed)
+		salaryMsg = b.PayGoSalary(player, landed)
 	}
-	if b.Players[*currentSlot.Owner].InJail {
-		return 0, nil
+	from := player.Position
+	player.Position = ((player.Position+steps)%len(b.Slots) + len(b.Slots)) % len(b.Slots)
+	b.emit(EventPlayerMoved, player, PlayerMovedPayload{From: from, To: player.Position, PassedGo: passedGo, Salary: salary})
+
+	msg, prompt, err := b.LandOnSlot(player)
+	return joinMessages(salaryMsg, msg), prompt, err
+}
+
+// AdvanceTo moves the player forward to the given slot, collecting the Go salary if they pass it.
+func (b *Board) AdvanceTo(player *Player, position int) (string, string, error) {
+	steps := (position - player.Position + len(b.Slots)) % len(b.Slots)
+	if steps == 0 && position == 0 {
+		// Already on Go, still a full lap
+		steps = len(b.Slots)
 	}
-	switch currentSlot.State {
-	case 0:
-		return currentSlot.Rent1, nil
-	case 1:
-		return currentSlot.Rent2, nil
-	case 2:
-		return currentSlot.Rent3, nil
-	case 3:
-		return currentSlot.Rent4, nil
-	case 4:
-		return currentSlot.Rent5, nil
-	default:
-		return -1, fmt.Errorf("invalid state")
+	return b.MovePlayer(player, steps)
+}
+
+// PayGoSalary credits the Go salary to the player, doubled on an exact landing if the house rule is on.
+func (b *Board) PayGoSalary(player *Player, landed bool) string {
+	salary := b.goSalary(landed)
+	// Paying from the bank cannot fail
+	b.transact(Transaction{To: player, Money: salary})
+	b.emit(EventSalaryCollected, player, SalaryPayload{Amount: salary, Landed: landed})
+	// Traded rights are counted in laps of their holder
+	lapMsg := b.countLap(player)
+	if landed {
+		return joinMessages(fmt.Sprintf("%s landed on Go and collected %d salary", player.Name, salary), lapMsg)
 	}
+	return joinMessages(fmt.Sprintf("%s passed Go and collected %d salary", player.Name, salary), lapMsg)
 }
 
-func (b *Board) MovePlayer(player *Player, steps int) (string, string, error) {
-	player.Position = (player.Position + steps) % len(b.Slots)
+// goSalary is the Go salary for passing Go, or for landing right on it.
+func (b *Board) goSalary(landed bool) int {
+	if landed && b.Rules.DoubleSalaryOnGo {
+		return b.Rules.GoSalary * 2
+	}
+	return b.Rules.GoSalary
+}
+
+// LandOnSlot resolves the slot at the player's current position.
+func (b *Board) LandOnSlot(player *Player) (string, string, error) {
 	currentSlot := b.Slots[player.Position]
 
 	switch currentSlot.Type {
-	case SlotTypeProperty:
+	case SlotTypeProperty, SlotTypeRailroad, SlotTypeUtility:
 		if currentSlot.Owner == nil && currentSlot.Price > 0 {
-			// prompt user
-			return "", fmt.Sprintf("Want to buy %s for %d?", currentSlot.Name, currentSlot.Price), nil
+			// prompt user, declining puts the property up for auction
+			position := player.Position
+			b.PendingPurchase = &position
+			prompt := fmt.Sprintf("Want to buy %s for %d?", currentSlot.Name, currentSlot.Price)
+			if b.Rules.AuctionOnDecline {
+				prompt += " Decline to auction it"
+			}
+			return "", prompt, nil
 		} else if currentSlot.Owner != nil && currentSlot.Owner != player.Id {
-			// Pay rent (simple calculation)
-			// TODO:
-			// proper rent calculation
-
 			rent, err := b.calculateRent(currentSlot)
 			if err != nil {
 				return "", "", err
@@ -560,18 +851,17 @@ func (b *Board) MovePlayer(player *Player, steps int) (string, string, error) {
 
 			for _, p := range b.Players {
 				if p.Id == currentSlot.Owner {
-					_, err := b.TransferPlayerToPlayer(player, p, rent)
-					if err != nil {
-						return "", "", err
-					}
-					return fmt.Sprintf("%s paid %d rent to %d", player.Name, rent, currentSlot.Owner), "", nil
+					return b.payRent(player, p, player.Position, rent)
 				}
 			}
 		}
+		return fmt.Sprintf("%s landed on %s", player.Name, currentSlot.Name), "", nil
 	case SlotTypeCard:
 		return b.HandleCardSlot(player, currentSlot)
 	case SlotTypeJail:
 		return b.HandleJailSlot(player, currentSlot)
+	case SlotTypeGoToJail:
+		return b.HandleGoToJailSlot(player, currentSlot)
 	case SlotTypeTax:
 		return b.HandleTaxSlot(player, currentSlot)
 	case SlotTypeNeutral:
@@ -581,70 +871,90 @@ func (b *Board) MovePlayer(player *Player, steps int) (string, string, error) {
 }
 
 func (b *Board) HandleGo(player *Player) (string, string, error) {
-	if b.MoveLock {
-		return "", "", fmt.Errorf("cant go now")
+	roll := b.RollDice()
+	rollMsg := rollMessage(player, roll)
+	b.emit(EventDiceRolled, player, DiceRolledPayload{DiceRoll: roll, Total: roll.Total(), Doubles: roll.IsDouble()})
+
+	// The roll counts even when the slot landed on cannot be resolved:
+	// the player has moved, so the turn moves on and the error is only reported
+	if player.InJail {
+		msg, prompt, err := b.rollInJail(player, roll)
+		b.settle(b.afterRollPhase())
+		return joinMessages(rollMsg, msg), prompt, err
 	}
-	b.LockPlayerMove(player)
-	// Player moving shouldn't be unlocked after move
-	// Only after the player has finished his turn
-	// So End turn Should have checks
-	// defer b.UnlockPlayerMove(player)
 
-	steps := b.RollDice()
-	// TODO:
-	// Handle Double
+	if roll.IsDouble() {
+		b.Doubles++
+		if b.Doubles >= MaxConsecutiveDoubles {
+			msg := b.SendToJail(player) + " for speeding"
+			b.settle(PhaseCanEnd)
+			return joinMessages(rollMsg, msg), b.jailPrompt(player), nil
+		}
+	}
 
-	return b.MovePlayer(player, steps)
+	msg, prompt, err := b.MovePlayer(player, roll.Total())
+	// A double earns another roll, unless the move itself ended in jail
+	if b.rollAgain() {
+		msg = joinMessages(msg, fmt.Sprintf("%s rolled doubles and goes again", player.Name))
+	}
+	b.settle(b.afterRollPhase())
+	return joinMessages(rollMsg, msg), prompt, err
 }
 
+// HandleEndTurn passes the turn to the next player once nothing is left open.
+// Ending the turn with a purchase pending declines it, and during a debt it
+// bankrupts debtors who can never pay.
 func (b *Board) HandleEndTurn(player *Player) (string, string, error) {
-	// TODO: player end turn checks
-	// Player cant end turn if
-	// 1. he hasnt moved
-	// 2. he hasnt bought/auctioned property
-	// 3. he hasnt paid rent / balance <0
-	// So UnlockTurnDone must be called form
-	// Either
-	// in jail and Complete Jail Prompt
-	// OR
-	// 1. No double
-	// AND
-	// 2. Moved to a slot
-	// AND
-	// 2. Bought Property
-	// OR
-	// 2. Paid Rent
-
-	if !b.TurnDone {
-		return "", "", fmt.Errorf("turn not done")
-	}
-	b.NextTurn()
-	return fmt.Sprintf("Waiting for %s to play", b.CurrentPlayer().Name), "", nil
-}
-
-// Placeholder function for handling card slots
-func (b *Board) HandleCardSlot(player *Player, slot Slot) (string, string, error) {
-	// TODO: Implement card slot logic
-	return "", "", nil
+	switch b.Phase {
+	case PhaseAwaitingPurchase:
+		return b.DeclinePurchase(player)
+	case PhaseDebt:
+		msg, err := b.settleDebts()
+		b.refreshPhase()
+		return msg, "", err
+	}
+	msg := b.nextTurn()
+	return joinMessages(msg, fmt.Sprintf("Waiting for %s to play", b.Players[b.Turn].Name)), "", nil
 }
 
-// Placeholder function for handling jail slots
-func (b *Board) HandleJailSlot(player *Player, slot Slot) (string, string, error) {
-	if player.InJail {
-		player.JailTurns++
-		if player.JailTurns >= 3 {
-			player.InJail = false
-			player.JailTurns = 0
-			b.UnlockPlayerMove(player)
-			return fmt.Sprintf("%s is released from jail after serving time", player.Name), "", nil
+// HandleCardSlot draws the top card of the slot's deck.
+// Keep cards go to the player's inventory, any other card takes effect and is discarded.
+func (b *Board) HandleCardSlot(player *Player, slot Slot) (string, string, error) {
+	card, err := b.drawCard(slot.Deck)
+	if err != nil {
+		return "", "", err
+	}
+	drawMsg := fmt.Sprintf("%s drew %s: %s", player.Name, card.Name, card.Description)
+	b.emit(EventCardDrawn, player, CardDrawnPayload{Card: card.Id, Name: card.Name, Deck: card.Deck, Keep: card.Keep})
+	if card.Keep {
+		if err := b.transact(Transaction{To: player, Cards: []IdType{card.Id}}); err != nil {
+			return "", "", err
 		}
-		return fmt.Sprintf("%s is in jail for %d more turns", player.Name, 3-player.JailTurns), "", nil
+		return joinMessages(drawMsg, fmt.Sprintf("%s keeps the card", player.Name)), "", nil
 	}
+
+	// Discard first, the effect may move the player onto another card slot
+	b.discardCard(card.Id)
+	msg, prompt, err := card.Effect(player, b)
+	return joinMessages(drawMsg, msg), prompt, err
+}
+
+// HandleJailSlot handles landing on the jail slot, which only means visiting.
+// Players in jail are sent there by SendToJail and marked InJail.
+func (b *Board) HandleJailSlot(player *Player, slot Slot) (string, string, error) {
+	return fmt.Sprintf("%s is just visiting %s", player.Name, slot.Name), "", nil
+}
+
+// SendToJail puts the player in jail and ends their movement for the turn.
+func (b *Board) SendToJail(player *Player) string {
 	player.InJail = true
 	player.JailTurns = 0
-	player.Position = b.findJailSlotPosition()
-	b.LockPlayerMove(player)
-	return fmt.Sprintf("%s has been sent to jail", player.Name), "", nil
+	if pos := b.findJailSlotPosition(); pos >= 0 {
+		player.Position = pos
+	}
+	b.Doubles = 0
+	b.emit(EventPlayerJailed, player, nil)
+	return fmt.Sprintf("%s has been sent to jail", player.Name)
 }
 
 // Helper function to find the position of the jail slot
@@ -657,18 +967,23 @@ func (b *Board) findJailSlotPosition() int {
 	return -1 // Return -1 if no jail slot is found
 }
 
-// Function for handling tax slots
-func (b *Board) HandleTaxSlot(player *Player, slot Slot) (string, string, error) {
-	err := b.TransferPlayerToBank(player, slot.Price)
-	if err != nil {
-		return "", "", err
-	}
-	return fmt.Sprintf("%s paid %d in taxes", player.Name, slot.Price), "", nil
-}
-
-// Placeholder function for handling neutral slots
-
+// HandleNeutralSlot handles slots that do nothing by themselves, except
+// Free Parking paying out the jackpot under the house rule.
 func (b *Board) HandleNeutralSlot(player *Player, slot Slot) (string, string, error) {
+	if slot.Kind == NeutralKindFreeParking && b.Rules.FreeParkingJackpot && b.Jackpot > 0 {
+		return b.collectJackpot(player, slot), "", nil
+	}
 	// Return a message indicating the player has landed on a neutral slot
 	return fmt.Sprintf("%s has landed on a neutral slot: %s", player.Name, slot.Name), "", nil
 }
+
+// joinMessages joins the non-empty messages into a single broadcast, one per line.
+func joinMessages(messages ...string) string {
+	parts := []string{}
+	for _, m := range messages {
+		if m != "" {
+			parts = append(parts, m)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
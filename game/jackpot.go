@@ -0,0 +1,53 @@
+package game
+
+import "fmt"
+
+// NeutralKind tells neutral slots apart.
+type NeutralKind string
+
+const (
+	NeutralKindGo          NeutralKind = "go"
+	NeutralKindFreeParking NeutralKind = "free_parking"
+)
+
+// addToJackpot puts fine money into the Free Parking pot when the house rule is on.
+// It reports whether the money went to the pot rather than the bank.
+func (b *Board) addToJackpot(amount int) bool {
+	if !b.Rules.FreeParkingJackpot {
+		return false
+	}
+	b.Jackpot += amount
+	return true
+}
+
+// payFine charges a tax or fine to the bank, or to the Free Parking jackpot under the house rule.
+// Once paid it emits the event, tax_paid or fine_paid, with where the player was charged.
+// A player who cannot pay owes it as a debt.
+func (b *Board) payFine(player *Player, amount int, reason string, event EventType) (string, string, error) {
+	if player.Money < amount {
+		kind := DebtKindFine
+		if event == EventTaxPaid {
+			kind = DebtKindTax
+		}
+		return b.recordDebt(player, nil, amount, kind, reason)
+	}
+	if err := b.transact(Transaction{From: player, Money: amount}); err != nil {
+		return "", "", err
+	}
+	msg := fmt.Sprintf("%s paid %d in %s", player.Name, amount, reason)
+	jackpot := b.addToJackpot(amount)
+	if jackpot {
+		msg += fmt.Sprintf(", the Free Parking jackpot is now %d", b.Jackpot)
+	}
+	b.emit(event, player, FeePayload{Slot: player.Position, Amount: amount, Reason: reason, Jackpot: jackpot})
+	return msg, "", nil
+}
+
+// collectJackpot pays the whole Free Parking pot to the player.
+func (b *Board) collectJackpot(player *Player, slot Slot) string {
+	jackpot := b.Jackpot
+	b.Jackpot = 0
+	b.transact(Transaction{To: player, Money: jackpot})
+	b.emit(EventJackpotWon, player, JackpotPayload{Slot: player.Position, Amount: jackpot})
+	return fmt.Sprintf("%s landed on %s and won the %d jackpot", player.Name, slot.Name, jackpot)
+}
@@ -0,0 +1,53 @@
+package game
+
+import "fmt"
+
+// jailPrompt lists the ways out of jail for a jailed player.
+func (b *Board) jailPrompt(player *Player) string {
+	return fmt.Sprintf("You are in jail: pay the %d fine, roll for doubles (attempt %d of %d) or use a Jail Free Card",
+		b.Rules.JailFine, player.JailTurns+1, b.Rules.MaxJailTurns)
+}
+
+// HandleGoToJailSlot sends the player who lands on it to jail.
+func (b *Board) HandleGoToJailSlot(player *Player, slot Slot) (string, string, error) {
+	return b.SendToJail(player), b.jailPrompt(player), nil
+}
+
+// PayJailFine releases the player from jail before they roll, so they can move normally.
+func (b *Board) PayJailFine(player *Player) (string, string, error) {
+	if !player.InJail {
+		return "", "", fmt.Errorf("not in jail")
+	}
+	if err := b.transact(Transaction{From: player, Money: b.Rules.JailFine}); err != nil {
+		return "", "", err
+	}
+	jackpot := b.addToJackpot(b.Rules.JailFine)
+	b.emit(EventFinePaid, player, FeePayload{Slot: player.Position, Amount: b.Rules.JailFine, Reason: "jail fines", Jackpot: jackpot})
+	player.InJail = false
+	player.JailTurns = 0
+	b.settle(PhasePreRoll)
+	return fmt.Sprintf("%s paid the %d fine and left jail", player.Name, b.Rules.JailFine), "", nil
+}
+
+// rollInJail lets a jailed player try to roll their way out.
+// Doubles release the player and move them, but do not grant another roll.
+// After the last failed attempt the fine is forced and the player moves anyway.
+func (b *Board) rollInJail(player *Player, roll DiceRoll) (string, string, error) {
+	releaseMsg := fmt.Sprintf("%s rolled doubles and left jail", player.Name)
+	fineMsg, finePrompt := "", ""
+	if !roll.IsDouble() {
+		player.JailTurns++
+		if player.JailTurns < b.Rules.MaxJailTurns {
+			return fmt.Sprintf("%s stays in jail", player.Name), b.jailPrompt(player), nil
+		}
+		releaseMsg = fmt.Sprintf("%s must pay the %d fine after %d failed attempts", player.Name, b.Rules.JailFine, b.Rules.MaxJailTurns)
+		var debtPrompt string
+		fineMsg, debtPrompt, _ = b.payFine(player, b.Rules.JailFine, "jail fines", EventFinePaid)
+		finePrompt = joinMessages(fmt.Sprintf("That was your last attempt, you are charged the %d fine and move %d",
+			b.Rules.JailFine, roll.Total()), debtPrompt)
+	}
+	player.InJail = false
+	player.JailTurns = 0
+	msg, prompt, err := b.MovePlayer(player, roll.Total())
+	return joinMessages(releaseMsg, fineMsg, msg), joinMessages(finePrompt, prompt), err
+}
@@ -0,0 +1,37 @@
+package game
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestJailPrompts(t *testing.T) {
+	b, a, _ := turnBoard()
+	b.SendToJail(a)
+	b.drainEvents()
+
+	b.startTurn()
+	if b.Phase != PhaseJailDecision {
+		t.Errorf("turn starts in %s", b.Phase)
+	}
+	notice := slices.IndexFunc(b.drainEvents(), func(e Event) bool {
+		return e.Type == EventNotice && *e.Target == *a.Id && strings.HasPrefix(e.Text, "You are in jail")
+	})
+	if notice == -1 {
+		t.Error("no jail prompt at the start of the turn")
+	}
+
+	a.JailTurns = b.Rules.MaxJailTurns - 1
+	money := a.Money
+	_, prompt, err := b.rollInJail(a, DiceRoll{Die1: 1, Die2: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.InJail || a.Money != money-b.Rules.JailFine {
+		t.Errorf("player is in jail %v with %d", a.InJail, a.Money)
+	}
+	if !strings.Contains(prompt, "fine") {
+		t.Errorf("prompt %q does not mention the forced fine", prompt)
+	}
+}
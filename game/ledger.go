@@ -0,0 +1,167 @@
+package game
+
+import "fmt"
+
+// Transaction moves money, properties and cards from one party to another.
+// A nil From or To is the bank: the bank can always pay, owns every unowned
+// property, and cards it receives go back to their deck's discard pile.
+type Transaction struct {
+	From       *Player
+	To         *Player
+	Money      int
+	Properties []IdType
+	Cards      []IdType
+	// Buildings are From's properties whose houses and hotels go back to the bank
+	// at half their cost, before anything else in the set changes hands
+	Buildings []IdType
+}
+
+// partyName names one side of a transaction.
+func partyName(player *Player) string {
+	if player == nil {
+		return "the bank"
+	}
+	return player.Name
+}
+
+// Transact applies a set of transactions all together, or none of them if any cannot be made.
+func (b *Board) Transact(transactions ...Transaction) error {
+	b.Lock()
+	defer b.Unlock()
+	return b.transact(transactions...)
+}
+
+// transact is Transact for callers that already hold the board lock.
+// Everything is checked before anything moves, so a failed transaction leaves the board untouched.
+// Money is checked on balance, what a player receives in the same call can pay for what they give.
+func (b *Board) transact(transactions ...Transaction) error {
+	if err := b.checkTransactions(transactions); err != nil {
+		return err
+	}
+
+	for _, t := range transactions {
+		for _, property := range t.Buildings {
+			b.returnBuildings(t.From, *property)
+		}
+	}
+	for _, t := range transactions {
+		if t.From != nil {
+			t.From.Money -= t.Money
+		}
+		if t.To != nil {
+			t.To.Money += t.Money
+		}
+		for _, property := range t.Properties {
+			b.Slots[*property].Owner = nil
+			if t.To != nil {
+				b.Slots[*property].Owner = t.To.Id
+			}
+		}
+		for _, card := range t.Cards {
+			if t.From != nil {
+				removeCard(t.From, card)
+			}
+			if t.To != nil {
+				t.To.Inventory = append(t.To.Inventory, card)
+			} else {
+				b.discardCard(card)
+			}
+		}
+	}
+	return nil
+}
+
+// checkTransactions makes sure every transaction in the set can be made.
+// It must be called with the board locked.
+func (b *Board) checkTransactions(transactions []Transaction) error {
+	balances := map[*Player]int{}
+	movedProperties := map[int]bool{}
+	movedCards := map[int]bool{}
+	soldBuildings := map[int]bool{}
+	for _, t := range transactions {
+		for _, property := range t.Buildings {
+			slot, err := b.slotAt(property)
+			if err != nil {
+				return err
+			}
+			if t.From == nil || slot.Owner != t.From.Id {
+				return fmt.Errorf("%s does not own %s", partyName(t.From), slot.Name)
+			}
+			if soldBuildings[*property] {
+				return fmt.Errorf("the buildings on %s are sold twice", slot.Name)
+			}
+			soldBuildings[*property] = true
+			balances[t.From] += b.buildingValue(slot)
+		}
+	}
+	for _, t := range transactions {
+		for _, party := range []*Player{t.From, t.To} {
+			if party != nil && b.GetPlayer(party.Id) == nil {
+				return fmt.Errorf("%s is no longer in the game", party.Name)
+			}
+		}
+		if t.Money < 0 {
+			return fmt.Errorf("invalid amount")
+		}
+		if t.From != nil {
+			balances[t.From] -= t.Money
+		}
+		if t.To != nil {
+			balances[t.To] += t.Money
+		}
+
+		for _, property := range t.Properties {
+			slot, err := b.slotAt(property)
+			if err != nil {
+				return err
+			}
+			if !slot.IsOwnable() {
+				return fmt.Errorf("%s cannot be owned", slot.Name)
+			}
+			if movedProperties[*property] {
+				return fmt.Errorf("%s changes hands twice", slot.Name)
+			}
+			movedProperties[*property] = true
+			var owner IdType
+			if t.From != nil {
+				owner = t.From.Id
+			}
+			if slot.Owner != owner {
+				return fmt.Errorf("%s does not own %s", partyName(t.From), slot.Name)
+			}
+			if slot.State > 0 && !soldBuildings[*property] {
+				return fmt.Errorf("sell the buildings on %s first", slot.Name)
+			}
+		}
+
+		for _, card := range t.Cards {
+			if b.cardById(card) == nil {
+				return fmt.Errorf("unknown card")
+			}
+			if movedCards[*card] {
+				return fmt.Errorf("%s changes hands twice", b.cardById(card).Name)
+			}
+			movedCards[*card] = true
+			if t.From != nil && !hasCards(t.From, card) {
+				return fmt.Errorf("%s does not hold %s", t.From.Name, b.cardById(card).Name)
+			}
+		}
+	}
+
+	for player, change := range balances {
+		if player.Money+change < 0 {
+			return fmt.Errorf("%s cannot pay %d", player.Name, -change)
+		}
+	}
+	return nil
+}
+
+// removeCard takes a card out of the player's inventory.
+func removeCard(player *Player, card IdType) {
+	for i, c := range player.Inventory {
+		if *c == *card {
+			player.Inventory = append(player.Inventory[:i], player.Inventory[i+1:]...)
+			return
+		}
+	}
+}
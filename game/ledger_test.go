@@ -0,0 +1,137 @@
+package game
+
+import (
+	"reflect"
+	"testing"
+)
+
+// ledgerBoard is a default board with two players: a owns both brown
+// properties, with a house on each, and holds a card.
+func ledgerBoard() (*Board, *Player, *Player) {
+	b := NewBoard()
+	a, c := b.AddPlayer("a"), b.AddPlayer("c")
+	for _, position := range []int{1, 3} {
+		b.Slots[position].Owner = a.Id
+		b.Slots[position].State = 1
+	}
+	b.Houses -= 2
+	card, err := b.drawCard("chance")
+	if err != nil {
+		panic(err)
+	}
+	a.Inventory = append(a.Inventory, card.Id)
+	return b, a, c
+}
+
+func id(n int) IdType {
+	return &n
+}
+
+func TestTransactFailureLeavesBoardUnchanged(t *testing.T) {
+	tests := []struct {
+		name         string
+		transactions func(b *Board, a, c *Player) []Transaction
+	}{
+		{
+			name: "insufficient funds",
+			transactions: func(b *Board, a, c *Player) []Transaction {
+				return []Transaction{{From: a, To: c, Money: a.Money + 1}}
+			},
+		},
+		{
+			name: "negative amount",
+			transactions: func(b *Board, a, c *Player) []Transaction {
+				return []Transaction{{From: a, To: c, Money: -10}}
+			},
+		},
+		{
+			name: "later transaction fails",
+			transactions: func(b *Board, a, c *Player) []Transaction {
+				return []Transaction{
+					{From: a, To: c, Money: 100, Cards: a.Inventory},
+					{From: c, To: a, Properties: []IdType{id(1)}},
+				}
+			},
+		},
+		{
+			name: "property changes hands twice",
+			transactions: func(b *Board, a, c *Player) []Transaction {
+				return []Transaction{
+					{From: a, To: c, Properties: []IdType{id(1)}, Buildings: []IdType{id(1)}},
+					{From: a, To: nil, Properties: []IdType{id(1)}},
+				}
+			},
+		},
+		{
+			name: "property with buildings",
+			transactions: func(b *Board, a, c *Player) []Transaction {
+				return []Transaction{{From: a, To: c, Money: 50, Properties: []IdType{id(1)}}}
+			},
+		},
+		{
+			name: "buildings sold by someone else",
+			transactions: func(b *Board, a, c *Player) []Transaction {
+				return []Transaction{{From: c, To: a, Buildings: []IdType{id(3)}}}
+			},
+		},
+		{
+			name: "unownable slot",
+			transactions: func(b *Board, a, c *Player) []Transaction {
+				return []Transaction{{From: nil, To: c, Properties: []IdType{id(0)}}}
+			},
+		},
+		{
+			name: "card not held",
+			transactions: func(b *Board, a, c *Player) []Transaction {
+				return []Transaction{{From: c, To: a, Cards: a.Inventory}}
+			},
+		},
+		{
+			name: "party left the game",
+			transactions: func(b *Board, a, c *Player) []Transaction {
+				gone := &Player{Id: id(99), Name: "gone", Money: 1000}
+				return []Transaction{{From: a, To: c, Money: 10}, {From: gone, To: a, Money: 10}}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, a, c := ledgerBoard()
+			before := b.snapshot()
+			discards := map[string]int{}
+			for name, deck := range b.Decks {
+				discards[name] = len(deck.Discard)
+			}
+
+			if err := b.transact(tt.transactions(b, a, c)...); err == nil {
+				t.Fatal("expected an error")
+			}
+			if after := b.snapshot(); !reflect.DeepEqual(before, after) {
+				t.Errorf("board changed:\nbefore %+v\nafter  %+v", before, after)
+			}
+			for name, deck := range b.Decks {
+				if len(deck.Discard) != discards[name] {
+					t.Errorf("%s discard pile changed", name)
+				}
+			}
+		})
+	}
+}
+
+func TestTransactSellsBuildingsWithTheProperty(t *testing.T) {
+	b, a, c := ledgerBoard()
+	houses := b.Houses
+	refund := b.buildingValue(&b.Slots[1])
+
+	err := b.transact(Transaction{From: a, To: c, Money: a.Money + refund, Properties: []IdType{id(1)}, Buildings: []IdType{id(1)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Money != 0 || c.Money != DefaultRules().StartingMoney*2+refund {
+		t.Errorf("money is %d and %d", a.Money, c.Money)
+	}
+	if b.Slots[1].Owner != c.Id || b.Slots[1].State != 0 || b.Houses != houses+1 {
+		t.Errorf("slot is %+v with %d houses in the bank", b.Slots[1], b.Houses)
+	}
+}
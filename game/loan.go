@@ -0,0 +1,312 @@
+package game
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// LoanStatus is where a loan stands, proposed until the other side accepts it.
+type LoanStatus string
+
+const (
+	LoanStatusProposed  LoanStatus = "proposed"
+	LoanStatusActive    LoanStatus = "active"
+	LoanStatusRepaid    LoanStatus = "repaid"
+	LoanStatusDefaulted LoanStatus = "defaulted"
+	LoanStatusRejected  LoanStatus = "rejected"
+	LoanStatusExpired   LoanStatus = "expired"
+)
+
+// Loan is money lent from one player to another, repaid with interest when it falls due.
+type Loan struct {
+	Id       int    `json:"id"`
+	Lender   IdType `json:"lender"`
+	Borrower IdType `json:"borrower"`
+	// ProposedBy is the lender or the borrower, the other side has to accept
+	ProposedBy IdType     `json:"proposedBy"`
+	Status     LoanStatus `json:"status"`
+	// Principal is the amount lent, Interest the percentage of it owed on top
+	Principal int `json:"principal"`
+	Interest  int `json:"interest"`
+	// Turns is how many turns after acceptance the loan falls due, DueTurn the turn it does
+	Turns   int `json:"turns"`
+	DueTurn int `json:"dueTurn,omitempty"`
+	// Collateral are the borrower's properties the lender takes if the loan is not repaid
+	Collateral []IdType `json:"collateral,omitempty"`
+	// Expires is when a proposed loan lapses if it has not been accepted
+	Expires time.Time `json:"expires"`
+}
+
+// Owed is what the borrower pays back when the loan falls due.
+func (l *Loan) Owed() int {
+	return l.Principal + l.Principal*l.Interest/100
+}
+
+// GameLoanBody proposes a loan. The proposer must be the lender or the borrower.
+type GameLoanBody struct {
+	Lender     IdType   `json:"lender" validate:"required"`
+	Borrower   IdType   `json:"borrower" validate:"required"`
+	Principal  int      `json:"principal" validate:"required"`
+	Interest   int      `json:"interest"`
+	Turns      int      `json:"turns" validate:"required"`
+	Collateral []IdType `json:"collateral,omitempty"`
+}
+
+// GameLoanIdBody names a proposed loan to accept or reject.
+type GameLoanIdBody struct {
+	LoanId IdType `json:"loanId" validate:"required"`
+}
+
+// loanById finds a loan, returning nil if there is none with that id.
+func (b *Board) loanById(id IdType) *Loan {
+	if id == nil {
+		return nil
+	}
+	return b.Loans[*id]
+}
+
+// sortedLoans lists the loans in the order they were proposed.
+func (b *Board) sortedLoans() []*Loan {
+	ids := []int{}
+	for id := range b.Loans {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	loans := []*Loan{}
+	for _, id := range ids {
+		loans = append(loans, b.Loans[id])
+	}
+	return loans
+}
+
+// counterparty is the side of a loan that did not propose it, and has to accept it.
+func (l *Loan) counterparty(proposer IdType) IdType {
+	if *l.Lender == *proposer {
+		return l.Borrower
+	}
+	return l.Lender
+}
+
+// ProposeLoan offers a loan to, or asks one of, another player.
+// It must be called with the board locked.
+func (b *Board) ProposeLoan(player *Player, body GameLoanBody) (string, string, error) {
+	lender, borrower := b.GetPlayer(body.Lender), b.GetPlayer(body.Borrower)
+	if lender == nil || borrower == nil {
+		return "", "", fmt.Errorf("player not found")
+	}
+	if *lender.Id == *borrower.Id {
+		return "", "", fmt.Errorf("cannot lend to yourself")
+	}
+	if *player.Id != *lender.Id && *player.Id != *borrower.Id {
+		return "", "", fmt.Errorf("you must be the lender or the borrower")
+	}
+	if body.Principal <= 0 || body.Interest < 0 || body.Turns <= 0 {
+		return "", "", fmt.Errorf("invalid loan terms")
+	}
+	for i, property := range body.Collateral {
+		slot, err := b.slotAt(property)
+		if err != nil {
+			return "", "", err
+		}
+		if slot.Owner != borrower.Id {
+			return "", "", fmt.Errorf("%s does not own %s", borrower.Name, slot.Name)
+		}
+		if containsId(body.Collateral[:i], property) {
+			return "", "", fmt.Errorf("%s is pledged twice", slot.Name)
+		}
+	}
+
+	b.nextLoanId++
+	loan := &Loan{
+		Id:         b.nextLoanId,
+		Lender:     lender.Id,
+		Borrower:   borrower.Id,
+		ProposedBy: player.Id,
+		Status:     LoanStatusProposed,
+		Principal:  body.Principal,
+		Interest:   body.Interest,
+		Turns:      body.Turns,
+		Collateral: body.Collateral,
+		Expires:    time.Now().Add(b.Rules.TradeDuration),
+	}
+	b.Loans[loan.Id] = loan
+	b.emit(EventLoanUpdated, player, *loan)
+
+	terms := b.describeLoan(loan)
+	b.notify(loan.counterparty(player.Id), "%s proposes loan %d: %s. Accept or reject it within %s",
+		player.Name, loan.Id, terms, b.Rules.TradeDuration)
+	return fmt.Sprintf("%s proposed loan %d: %s", player.Name, loan.Id, terms), fmt.Sprintf("Your loan has id %d", loan.Id), nil
+}
+
+// AcceptLoan accepts a proposed loan, paying out the principal to the borrower.
+// It must be called with the board locked.
+func (b *Board) AcceptLoan(player *Player, body GameLoanIdBody) (string, string, error) {
+	loan, err := b.proposedLoanFor(player, body)
+	if err != nil {
+		return "", "", err
+	}
+	lender, borrower := b.GetPlayer(loan.Lender), b.GetPlayer(loan.Borrower)
+	if err := b.transact(Transaction{From: lender, To: borrower, Money: loan.Principal}); err != nil {
+		return "", "", err
+	}
+	loan.Status = LoanStatusActive
+	loan.DueTurn = b.TurnNumber + loan.Turns
+	b.emit(EventLoanUpdated, player, *loan)
+
+	b.notify(loan.counterparty(player.Id), "%s accepted loan %d", player.Name, loan.Id)
+	return fmt.Sprintf("%s lent %s %d, %d due on turn %d", lender.Name, borrower.Name, loan.Principal, loan.Owed(), loan.DueTurn), "", nil
+}
+
+// RejectLoan turns down a proposed loan.
+// It must be called with the board locked.
+func (b *Board) RejectLoan(player *Player, body GameLoanIdBody) (string, string, error) {
+	loan, err := b.proposedLoanFor(player, body)
+	if err != nil {
+		return "", "", err
+	}
+	loan.Status = LoanStatusRejected
+	b.emit(EventLoanUpdated, player, *loan)
+	b.notify(loan.counterparty(player.Id), "%s rejected loan %d", player.Name, loan.Id)
+	return fmt.Sprintf("%s rejected loan %d", player.Name, loan.Id), "", nil
+}
+
+// proposedLoanFor finds a proposed loan waiting for the player's answer.
+// It must be called with the board locked.
+func (b *Board) proposedLoanFor(player *Player, body GameLoanIdBody) (*Loan, error) {
+	loan := b.loanById(body.LoanId)
+	if loan == nil || loan.Status != LoanStatusProposed {
+		return nil, fmt.Errorf("no proposed loan with that id")
+	}
+	if *loan.counterparty(loan.ProposedBy) != *player.Id {
+		return nil, fmt.Errorf("not your loan")
+	}
+	return loan, nil
+}
+
+// describeLoan spells out the terms of a loan.
+func (b *Board) describeLoan(loan *Loan) string {
+	terms := fmt.Sprintf("%s lends %s %d at %d%% interest, %d due after %d turns",
+		b.playerName(loan.Lender), b.playerName(loan.Borrower), loan.Principal, loan.Interest, loan.Owed(), loan.Turns)
+	names := []string{}
+	for _, property := range loan.Collateral {
+		if slot, err := b.slotAt(property); err == nil {
+			names = append(names, slot.Name)
+		}
+	}
+	if len(names) > 0 {
+		terms += ", secured on " + strings.Join(names, ", ")
+	}
+	return terms
+}
+
+// collectLoans repays the loans falling due this turn. A borrower who cannot
+// pay defaults: the lender takes the collateral they still own for what it is
+// worth, and the borrower owes the rest as a debt.
+// It must be called with the board locked.
+func (b *Board) collectLoans() string {
+	messages := []string{}
+	for _, loan := range b.sortedLoans() {
+		if loan.Status != LoanStatusActive || b.TurnNumber < loan.DueTurn {
+			continue
+		}
+		lender, borrower := b.GetPlayer(loan.Lender), b.GetPlayer(loan.Borrower)
+		if err := b.transact(Transaction{From: borrower, To: lender, Money: loan.Owed()}); err == nil {
+			loan.Status = LoanStatusRepaid
+			b.emit(EventLoanUpdated, borrower, *loan)
+			messages = append(messages, fmt.Sprintf("%s repaid loan %d of %d to %s", borrower.Name, loan.Id, loan.Owed(), lender.Name))
+			continue
+		}
+
+		loan.Status = LoanStatusDefaulted
+		b.emit(EventLoanUpdated, borrower, *loan)
+		seizure := Transaction{From: borrower, To: lender}
+		names := []string{}
+		worth := 0
+		for _, property := range loan.Collateral {
+			slot := &b.Slots[*property]
+			if slot.Owner != borrower.Id {
+				continue
+			}
+			seizure.Properties = append(seizure.Properties, property)
+			if slot.State > 0 {
+				seizure.Buildings = append(seizure.Buildings, property)
+			}
+			names = append(names, slot.Name)
+			// A mortgage on the collateral is the lender's to lift
+			worth += slot.Price
+			if slot.Mortgaged {
+				worth -= slot.UnmortgageCost()
+			}
+		}
+		msg := fmt.Sprintf("%s defaulted on loan %d from %s", borrower.Name, loan.Id, lender.Name)
+		if err := b.transact(seizure); err != nil {
+			// Nothing was taken, the whole loan is owed
+			msg += fmt.Sprintf(", the collateral could not be taken: %v", err)
+			worth = 0
+		} else if len(names) > 0 {
+			msg += fmt.Sprintf(", who takes %s", strings.Join(names, ", "))
+		}
+		messages = append(messages, msg)
+
+		shortfall := loan.Owed() - worth
+		if shortfall <= 0 {
+			continue
+		}
+		if err := b.transact(Transaction{From: borrower, To: lender, Money: shortfall}); err == nil {
+			messages = append(messages, fmt.Sprintf("%s paid the remaining %d to %s", borrower.Name, shortfall, lender.Name))
+			continue
+		}
+		debtMsg, prompt, _ := b.recordDebt(borrower, lender, shortfall, DebtKindLoan, "loan repayment")
+		b.notify(borrower.Id, "%s", prompt)
+		messages = append(messages, debtMsg)
+	}
+	return strings.Join(messages, "\n")
+}
+
+// expireLoans drops the loan proposals nobody accepted in time.
+// It must be called with the board locked.
+func (b *Board) expireLoans(now time.Time) string {
+	messages := []string{}
+	for _, loan := range b.sortedLoans() {
+		if loan.Status != LoanStatusProposed || now.Before(loan.Expires) {
+			continue
+		}
+		loan.Status = LoanStatusExpired
+		b.emit(EventLoanUpdated, nil, *loan)
+		messages = append(messages, fmt.Sprintf("Loan %d expired", loan.Id))
+	}
+	return strings.Join(messages, "\n")
+}
+
+// endLoans closes the loans of players who left the game. Their debts die with
+// them and what they were owed is forgiven.
+// It must be called with the board locked.
+func (b *Board) endLoans() {
+	for _, loan := range b.Loans {
+		if loan.Status != LoanStatusProposed && loan.Status != LoanStatusActive {
+			continue
+		}
+		if b.GetPlayer(loan.Lender) == nil || b.GetPlayer(loan.Borrower) == nil {
+			loan.Status = LoanStatusDefaulted
+		}
+	}
+}
+
+// loanBalance is what a player is owed in outstanding loans, less what they owe.
+func (b *Board) loanBalance(player *Player) int {
+	balance := 0
+	for _, loan := range b.Loans {
+		if loan.Status != LoanStatusActive {
+			continue
+		}
+		if *loan.Lender == *player.Id {
+			balance += loan.Owed()
+		}
+		if *loan.Borrower == *player.Id {
+			balance -= loan.Owed()
+		}
+	}
+	return balance
+}
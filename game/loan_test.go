@@ -0,0 +1,59 @@
+package game
+
+import "testing"
+
+func TestProposeLoanCollateral(t *testing.T) {
+	tests := []struct {
+		name       string
+		collateral []IdType
+		ok         bool
+	}{
+		{name: "owned", collateral: []IdType{id(1), id(3)}, ok: true},
+		{name: "pledged twice", collateral: []IdType{id(1), id(1)}},
+		{name: "missing id", collateral: []IdType{nil}},
+		{name: "not owned", collateral: []IdType{id(6)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, a, c := ledgerBoard()
+			body := GameLoanBody{Lender: c.Id, Borrower: a.Id, Principal: 100, Turns: 1, Collateral: tt.collateral}
+			_, _, err := b.ProposeLoan(a, body)
+			if (err == nil) != tt.ok {
+				t.Fatalf("ProposeLoan error = %v, want ok %v", err, tt.ok)
+			}
+			if len(b.Loans) != map[bool]int{true: 1}[tt.ok] {
+				t.Errorf("%d loans proposed", len(b.Loans))
+			}
+		})
+	}
+}
+
+func TestLoanDefaultSeizesCollateral(t *testing.T) {
+	b, a, c := ledgerBoard()
+	body := GameLoanBody{Lender: c.Id, Borrower: a.Id, Principal: 1000, Interest: 10, Turns: 1, Collateral: []IdType{id(1)}}
+	if _, _, err := b.ProposeLoan(a, body); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := b.AcceptLoan(c, GameLoanIdBody{LoanId: id(b.nextLoanId)}); err != nil {
+		t.Fatal(err)
+	}
+	a.Money = 0
+	lenderMoney := c.Money
+	b.TurnNumber++
+	b.collectLoans()
+
+	if b.Loans[b.nextLoanId].Status != LoanStatusDefaulted {
+		t.Errorf("loan is %s", b.Loans[b.nextLoanId].Status)
+	}
+	// The house on the collateral is sold back to the bank for the borrower
+	if b.Slots[1].Owner != c.Id || b.Slots[1].State != 0 {
+		t.Errorf("collateral is %+v", b.Slots[1])
+	}
+	if c.Money != lenderMoney {
+		t.Errorf("lender has %d, want %d", c.Money, lenderMoney)
+	}
+	debt := b.debtOf(a)
+	if debt == nil || *debt.Creditor != *c.Id || debt.Amount != 1100-b.Slots[1].Price || debt.Kind != DebtKindLoan {
+		t.Errorf("debt is %+v", debt)
+	}
+}
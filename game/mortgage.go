@@ -0,0 +1,76 @@
+package game
+
+import "fmt"
+
+// MortgageInterestPercent is charged on top of the principal to lift a mortgage,
+// and alone when a mortgaged property changes hands.
+const MortgageInterestPercent = 10
+
+// MortgageValue is what the bank lends against a property, half its price.
+func (s Slot) MortgageValue() int {
+	return s.Price / 2
+}
+
+// mortgageInterest is the interest due on a mortgaged property.
+func (s Slot) mortgageInterest() int {
+	return s.MortgageValue() * MortgageInterestPercent / 100
+}
+
+// UnmortgageCost is the principal plus interest needed to lift the mortgage.
+func (s Slot) UnmortgageCost() int {
+	return s.MortgageValue() + s.mortgageInterest()
+}
+
+// MortgageProperty mortgages an owned property for half its price.
+// Every property in the color group must be free of buildings first.
+// It must be called with the board locked.
+func (b *Board) MortgageProperty(player *Player, body GamePropertyBody) (string, string, error) {
+	slot, err := b.slotAt(body.Property)
+	if err != nil {
+		return "", "", err
+	}
+	if slot.Owner != player.Id {
+		return "", "", fmt.Errorf("not owner of property")
+	}
+	if slot.Mortgaged {
+		return "", "", fmt.Errorf("%s is already mortgaged", slot.Name)
+	}
+	if slot.Group != "" {
+		for _, i := range b.groupSlots(slot.Group) {
+			if b.Slots[i].State > 0 {
+				return "", "", fmt.Errorf("sell the buildings in the %s group first", slot.Group)
+			}
+		}
+	}
+
+	value := slot.MortgageValue()
+	if err := b.transact(Transaction{To: player, Money: value}); err != nil {
+		return "", "", err
+	}
+	slot.Mortgaged = true
+	b.emit(EventPropertyMortgaged, player, MortgagePayload{Property: *body.Property, Amount: value})
+	return fmt.Sprintf("%s mortgaged %s for %d", player.Name, slot.Name, value), "", nil
+}
+
+// UnmortgageProperty lifts the mortgage on a property for the principal plus interest.
+// It must be called with the board locked.
+func (b *Board) UnmortgageProperty(player *Player, body GamePropertyBody) (string, string, error) {
+	slot, err := b.slotAt(body.Property)
+	if err != nil {
+		return "", "", err
+	}
+	if slot.Owner != player.Id {
+		return "", "", fmt.Errorf("not owner of property")
+	}
+	if !slot.Mortgaged {
+		return "", "", fmt.Errorf("%s is not mortgaged", slot.Name)
+	}
+
+	cost := slot.UnmortgageCost()
+	if err := b.transact(Transaction{From: player, Money: cost}); err != nil {
+		return "", "", err
+	}
+	slot.Mortgaged = false
+	b.emit(EventPropertyUnmortgaged, player, MortgagePayload{Property: *body.Property, Amount: cost})
+	return fmt.Sprintf("%s lifted the mortgage on %s for %d", player.Name, slot.Name, cost), "", nil
+}
@@ -0,0 +1,76 @@
+package game
+
+import "fmt"
+
+// IsOwnable reports whether a slot can be bought and charge rent.
+func (s Slot) IsOwnable() bool {
+	switch s.Type {
+	case SlotTypeProperty, SlotTypeRailroad, SlotTypeUtility:
+		return s.Price > 0
+	}
+	return false
+}
+
+// rentTier returns the Rent1..Rent5 value for a zero-based tier.
+func (s Slot) rentTier(tier int) (int, error) {
+	switch tier {
+	case 0:
+		return s.Rent1, nil
+	case 1:
+		return s.Rent2, nil
+	case 2:
+		return s.Rent3, nil
+	case 3:
+		return s.Rent4, nil
+	case 4:
+		return s.Rent5, nil
+	default:
+		return -1, fmt.Errorf("invalid state")
+	}
+}
+
+// countOwned counts the slots of a type that the player owns.
+func (b *Board) countOwned(player *Player, slotType Slottype) int {
+	count := 0
+	for _, slot := range b.Slots {
+		if slot.Type == slotType && slot.Owner == player.Id {
+			count++
+		}
+	}
+	return count
+}
+
+// calculateRent works out the rent owed for landing on a slot.
+//
+//   - Properties charge the tier for their State, doubled while unimproved if the owner holds the whole color group.
+//   - Railroads charge Rent1..Rent4 by how many railroads the owner holds.
+//   - Utilities multiply the dice roll by Rent1 with one utility owned, Rent2 with two.
+func (b *Board) calculateRent(currentSlot Slot) (int, error) {
+	if currentSlot.Owner == nil || currentSlot.Mortgaged {
+		return 0, nil
+	}
+	owner := b.GetPlayer(currentSlot.Owner)
+	if owner.InJail && b.Rules.NoRentInJail {
+		return 0, nil
+	}
+
+	switch currentSlot.Type {
+	case SlotTypeRailroad:
+		return currentSlot.rentTier(b.countOwned(owner, SlotTypeRailroad) - 1)
+	case SlotTypeUtility:
+		multiplier, err := currentSlot.rentTier(b.countOwned(owner, SlotTypeUtility) - 1)
+		if err != nil {
+			return -1, err
+		}
+		return b.LastRoll.Total() * multiplier, nil
+	}
+
+	rent, err := currentSlot.rentTier(currentSlot.State)
+	if err != nil {
+		return -1, err
+	}
+	if currentSlot.State == 0 && b.ownsGroup(owner, currentSlot.Group) {
+		rent *= 2
+	}
+	return rent, nil
+}
@@ -0,0 +1,146 @@
+package game
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// AbandonPolicy decides what happens to a player whose connection is gone for good.
+type AbandonPolicy string
+
+const (
+	// AbandonForfeit takes the player out of the game as if they forfeited
+	AbandonForfeit AbandonPolicy = "forfeit"
+	// AbandonBot keeps the player in the game, played by a bot until they come back
+	AbandonBot AbandonPolicy = "bot"
+)
+
+// Rules are the house rules a room is played with, chosen by the host when the room is created.
+type Rules struct {
+	Name          string `json:"name"`
+	StartingMoney int    `json:"startingMoney"`
+	// GoSalary is paid to a player each time they pass or land on Go (slot 0)
+	GoSalary int `json:"goSalary"`
+	// DoubleSalaryOnGo pays twice the salary when a player lands exactly on Go
+	DoubleSalaryOnGo bool `json:"doubleSalaryOnGo"`
+	// JailFine is paid to leave jail, or forced after the last failed roll
+	JailFine int `json:"jailFine"`
+	// MaxJailTurns is how many turns a player may try to roll doubles before the fine is forced
+	MaxJailTurns int `json:"maxJailTurns"`
+	// NoRentInJail stops owners from collecting rent while they are in jail
+	NoRentInJail bool `json:"noRentInJail"`
+	// AuctionOnDecline auctions a property the current player does not buy, otherwise it stays with the bank
+	AuctionOnDecline bool `json:"auctionOnDecline"`
+	// AuctionDuration is how long an auction waits for a new bid before closing
+	AuctionDuration time.Duration `json:"auctionDuration"`
+	// MinBidIncrement is the least a bid must raise the current high bid by
+	MinBidIncrement int `json:"minBidIncrement"`
+	// TradeDuration is how long a trade offer stays open before it expires
+	TradeDuration time.Duration `json:"tradeDuration"`
+	// FreeParkingJackpot collects taxes and fines in a pot won by landing on Free Parking
+	FreeParkingJackpot bool `json:"freeParkingJackpot"`
+	// HouseSupply and HotelSupply are the buildings the bank holds at the start of a game
+	HouseSupply int `json:"houseSupply"`
+	HotelSupply int `json:"hotelSupply"`
+	// ReconnectGrace is how long a disconnected player is held for before Abandoned applies
+	ReconnectGrace time.Duration `json:"reconnectGrace"`
+	Abandoned      AbandonPolicy `json:"abandoned"`
+}
+
+// rulePresets are the named rule sets a host can pick from.
+var rulePresets = map[string]Rules{
+	"classic": {
+		Name:               "classic",
+		StartingMoney:      1500,
+		GoSalary:           200,
+		DoubleSalaryOnGo:   false,
+		JailFine:           50,
+		MaxJailTurns:       3,
+		NoRentInJail:       true,
+		AuctionOnDecline:   true,
+		AuctionDuration:    15 * time.Second,
+		MinBidIncrement:    10,
+		TradeDuration:      60 * time.Second,
+		FreeParkingJackpot: false,
+		HouseSupply:        32,
+		HotelSupply:        12,
+		ReconnectGrace:     2 * time.Minute,
+		Abandoned:          AbandonForfeit,
+	},
+	// speed gets to the end game quickly with more cash and shorter jail stays
+	"speed": {
+		Name:               "speed",
+		StartingMoney:      2500,
+		GoSalary:           400,
+		DoubleSalaryOnGo:   true,
+		JailFine:           50,
+		MaxJailTurns:       1,
+		NoRentInJail:       true,
+		AuctionOnDecline:   true,
+		AuctionDuration:    8 * time.Second,
+		MinBidIncrement:    25,
+		TradeDuration:      30 * time.Second,
+		FreeParkingJackpot: false,
+		HouseSupply:        32,
+		HotelSupply:        12,
+		ReconnectGrace:     30 * time.Second,
+		Abandoned:          AbandonForfeit,
+	},
+	// family is gentler, with no auctions, a cheap way out of jail and a Free Parking jackpot
+	"family": {
+		Name:               "family",
+		StartingMoney:      2000,
+		GoSalary:           200,
+		DoubleSalaryOnGo:   true,
+		JailFine:           25,
+		MaxJailTurns:       3,
+		NoRentInJail:       false,
+		AuctionOnDecline:   false,
+		AuctionDuration:    20 * time.Second,
+		MinBidIncrement:    5,
+		TradeDuration:      2 * time.Minute,
+		FreeParkingJackpot: true,
+		HouseSupply:        32,
+		HotelSupply:        12,
+		ReconnectGrace:     5 * time.Minute,
+		Abandoned:          AbandonBot,
+	},
+}
+
+// DefaultRules returns the classic rules.
+func DefaultRules() Rules {
+	return rulePresets["classic"]
+}
+
+// RulesPreset returns the named rule preset, or the classic rules for an empty name.
+func RulesPreset(name string) (Rules, error) {
+	if name == "" {
+		return DefaultRules(), nil
+	}
+	rules, ok := rulePresets[name]
+	if !ok {
+		return Rules{}, fmt.Errorf("unknown rules preset %q", name)
+	}
+	return rules, nil
+}
+
+// RulesPresetNames lists the available presets in alphabetical order.
+func RulesPresetNames() []string {
+	names := []string{}
+	for name := range rulePresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ApplyRules sets the rules for a game and refills the bank's buildings to match.
+// It is meant to be called before any player joins.
+func (b *Board) ApplyRules(rules Rules) {
+	b.Lock()
+	defer b.Unlock()
+	b.Rules = rules
+	b.Houses = rules.HouseSupply
+	b.Hotels = rules.HotelSupply
+}
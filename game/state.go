@@ -0,0 +1,235 @@
+package game
+
+import (
+	"reflect"
+	"sort"
+)
+
+// SlotState is what clients see of a slot.
+type SlotState struct {
+	Position  int         `json:"position"`
+	Name      string      `json:"name"`
+	Type      Slottype    `json:"type"`
+	Owner     IdType      `json:"owner"`
+	Price     int         `json:"price,omitempty"`
+	Group     string      `json:"group,omitempty"`
+	Kind      NeutralKind `json:"kind,omitempty"`
+	Mortgaged bool        `json:"mortgaged,omitempty"`
+	// State is the number of houses, HotelState for a hotel
+	State int `json:"state,omitempty"`
+}
+
+// PlayerState is what clients see of a player.
+type PlayerState struct {
+	Id        IdType   `json:"id"`
+	Name      string   `json:"name"`
+	Money     int      `json:"money"`
+	Position  int      `json:"position"`
+	InJail    bool     `json:"inJail,omitempty"`
+	JailTurns int      `json:"jailTurns,omitempty"`
+	Inventory []IdType `json:"inventory,omitempty"`
+}
+
+// TurnState is whose turn it is and how far it has got.
+type TurnState struct {
+	Player          IdType    `json:"player"`
+	Number          int       `json:"number"`
+	Phase           TurnPhase `json:"phase"`
+	LastRoll        DiceRoll  `json:"lastRoll"`
+	Doubles         int       `json:"doubles,omitempty"`
+	PendingPurchase IdType    `json:"pendingPurchase,omitempty"`
+	PendingTax      IdType    `json:"pendingTax,omitempty"`
+	// PendingPercentTax is the amount of the percent option of the pending tax
+	PendingPercentTax int `json:"pendingPercentTax,omitempty"`
+}
+
+// BankState is what the bank holds besides unowned properties.
+type BankState struct {
+	Houses  int `json:"houses"`
+	Hotels  int `json:"hotels"`
+	Jackpot int `json:"jackpot"`
+}
+
+// Snapshot is the whole game state as clients may see it.
+// It leaves out what players must not know, such as the order of the decks.
+type Snapshot struct {
+	Version      int             `json:"version"`
+	Turn         TurnState       `json:"turn"`
+	Slots        []SlotState     `json:"slots"`
+	Players      []PlayerState   `json:"players"`
+	Trades       []GameTradeBody `json:"trades"`
+	Auction      *Auction        `json:"auction"`
+	AuctionQueue []int           `json:"auctionQueue"`
+	Debts        []Debt          `json:"debts"`
+	Contracts    []Contract      `json:"contracts"`
+	Loans        []Loan          `json:"loans"`
+	Bank         BankState       `json:"bank"`
+}
+
+// StateDiff is what changed between two versions of the game state.
+// Sections that did not change are left out, slots and players are sent one by one.
+// A client whose version is not Base has missed a diff and should ask for the state again.
+type StateDiff struct {
+	Version int `json:"version"`
+	Base    int `json:"base"`
+	// Slots are the slots that changed
+	Slots []SlotState `json:"slots,omitempty"`
+	// Players are the players that changed or joined, Removed the ids of those who left
+	Players []PlayerState `json:"players,omitempty"`
+	Removed []IdType      `json:"removed,omitempty"`
+	// Sections holds every other part of the snapshot that changed, by its name in the snapshot
+	Sections map[string]interface{} `json:"sections,omitempty"`
+}
+
+// Snapshot returns the state as of the last version sent to clients.
+func (b *Board) Snapshot() Snapshot {
+	b.Lock()
+	defer b.Unlock()
+	if b.state == nil {
+		b.commitState()
+	}
+	return *b.state
+}
+
+// snapshot captures the current state. Everything is copied, so the board can move on.
+// It must be called with the board locked.
+func (b *Board) snapshot() *Snapshot {
+	s := &Snapshot{
+		Version: b.stateVersion,
+		Turn: TurnState{
+			Number:            b.TurnNumber,
+			Phase:             b.Phase,
+			LastRoll:          b.LastRoll,
+			Doubles:           b.Doubles,
+			PendingPurchase:   b.PendingPurchase,
+			PendingTax:        b.PendingTax,
+			PendingPercentTax: b.PendingPercentTax,
+		},
+		Slots:        []SlotState{},
+		Players:      []PlayerState{},
+		Trades:       []GameTradeBody{},
+		AuctionQueue: append([]int{}, b.AuctionQueue...),
+		Debts:        []Debt{},
+		Contracts:    []Contract{},
+		Loans:        []Loan{},
+		Bank:         BankState{Houses: b.Houses, Hotels: b.Hotels, Jackpot: b.Jackpot},
+	}
+	if len(b.Players) > 0 {
+		s.Turn.Player = b.Players[b.Turn].Id
+	}
+	for i, slot := range b.Slots {
+		s.Slots = append(s.Slots, SlotState{
+			Position:  i,
+			Name:      slot.Name,
+			Type:      slot.Type,
+			Owner:     slot.Owner,
+			Price:     slot.Price,
+			Group:     slot.Group,
+			Kind:      slot.Kind,
+			Mortgaged: slot.Mortgaged,
+			State:     slot.State,
+		})
+	}
+	for _, player := range b.Players {
+		s.Players = append(s.Players, PlayerState{
+			Id:        player.Id,
+			Name:      player.Name,
+			Money:     player.Money,
+			Position:  player.Position,
+			InJail:    player.InJail,
+			JailTurns: player.JailTurns,
+			Inventory: append([]IdType{}, player.Inventory...),
+		})
+	}
+	for _, trade := range b.openTrades() {
+		s.Trades = append(s.Trades, *trade)
+	}
+	if b.Auction != nil {
+		auction := *b.Auction
+		s.Auction = &auction
+	}
+	for _, debt := range b.Debts {
+		s.Debts = append(s.Debts, *debt)
+	}
+	for _, contract := range b.Contracts {
+		s.Contracts = append(s.Contracts, *contract)
+	}
+	for _, loan := range b.sortedLoans() {
+		s.Loans = append(s.Loans, *loan)
+	}
+	return s
+}
+
+// commitState takes a new snapshot and, if anything changed since the last one,
+// bumps the state version and emits the difference to clients.
+// It must be called with the board locked.
+func (b *Board) commitState() {
+	previous := b.state
+	current := b.snapshot()
+	if previous == nil {
+		b.state = current
+		return
+	}
+	diff := diffState(previous, current)
+	if diff == nil {
+		return
+	}
+	b.stateVersion++
+	current.Version = b.stateVersion
+	diff.Version = b.stateVersion
+	b.state = current
+	b.emit(EventStateDiff, nil, *diff)
+}
+
+// diffState compares two snapshots, returning nil if they are the same.
+func diffState(previous *Snapshot, current *Snapshot) *StateDiff {
+	diff := &StateDiff{Base: previous.Version, Sections: map[string]interface{}{}}
+	changed := false
+
+	for i, slot := range current.Slots {
+		if i >= len(previous.Slots) || !reflect.DeepEqual(previous.Slots[i], slot) {
+			diff.Slots = append(diff.Slots, slot)
+			changed = true
+		}
+	}
+
+	before := map[int]PlayerState{}
+	for _, player := range previous.Players {
+		before[*player.Id] = player
+	}
+	for _, player := range current.Players {
+		old, ok := before[*player.Id]
+		delete(before, *player.Id)
+		if !ok || !reflect.DeepEqual(old, player) {
+			diff.Players = append(diff.Players, player)
+			changed = true
+		}
+	}
+	for _, player := range before {
+		diff.Removed = append(diff.Removed, player.Id)
+		changed = true
+	}
+	sort.Slice(diff.Removed, func(i, j int) bool { return *diff.Removed[i] < *diff.Removed[j] })
+
+	sections := map[string][2]interface{}{
+		"turn":         {previous.Turn, current.Turn},
+		"trades":       {previous.Trades, current.Trades},
+		"auction":      {previous.Auction, current.Auction},
+		"auctionQueue": {previous.AuctionQueue, current.AuctionQueue},
+		"debts":        {previous.Debts, current.Debts},
+		"contracts":    {previous.Contracts, current.Contracts},
+		"loans":        {previous.Loans, current.Loans},
+		"bank":         {previous.Bank, current.Bank},
+	}
+	for name, pair := range sections {
+		if !reflect.DeepEqual(pair[0], pair[1]) {
+			diff.Sections[name] = pair[1]
+			changed = true
+		}
+	}
+
+	if !changed {
+		return nil
+	}
+	return diff
+}
@@ -0,0 +1,90 @@
+package game
+
+import "fmt"
+
+// TaxMode decides how a tax slot charges the player who lands on it.
+type TaxMode string
+
+const (
+	// TaxModeFlat charges the slot's Price, and is the default
+	TaxModeFlat TaxMode = "flat"
+	// TaxModePercent charges TaxPercent of the player's net worth
+	TaxModePercent TaxMode = "percent"
+	// TaxModeLesser and TaxModeGreater charge the lesser or greater of the two
+	TaxModeLesser  TaxMode = "lesser"
+	TaxModeGreater TaxMode = "greater"
+	// TaxModeChoice lets the player choose between the two
+	TaxModeChoice TaxMode = "choice"
+)
+
+// GameTaxBody is the player's choice of how to pay a TaxModeChoice tax.
+type GameTaxBody struct {
+	Option TaxMode `json:"option" validate:"required"`
+}
+
+// NetWorth values everything a player has: cash, property prices less any
+// mortgage, buildings at what they cost, and loans they are owed less loans they owe.
+func (b *Board) NetWorth(player *Player) int {
+	worth := player.Money + b.loanBalance(player)
+	for _, slot := range b.Slots {
+		if slot.Owner != player.Id {
+			continue
+		}
+		worth += slot.Price
+		if slot.Mortgaged {
+			worth -= slot.MortgageValue()
+		}
+		worth += slot.State * b.HouseCosts[slot.Group]
+	}
+	return worth
+}
+
+// percentTax is the percentage tax a slot charges the player.
+func (b *Board) percentTax(player *Player, slot Slot) int {
+	return b.NetWorth(player) * slot.TaxPercent / 100
+}
+
+// HandleTaxSlot charges the tax of the slot the player landed on.
+// Choice taxes prompt the player to pick flat or percent with the pay_tax action.
+func (b *Board) HandleTaxSlot(player *Player, slot Slot) (string, string, error) {
+	switch slot.TaxMode {
+	case TaxModePercent:
+		return b.payFine(player, b.percentTax(player, slot), "taxes", EventTaxPaid)
+	case TaxModeLesser:
+		return b.payFine(player, min(slot.Price, b.percentTax(player, slot)), "taxes", EventTaxPaid)
+	case TaxModeGreater:
+		return b.payFine(player, max(slot.Price, b.percentTax(player, slot)), "taxes", EventTaxPaid)
+	case TaxModeChoice:
+		// The net worth is taken now, selling up before paying does not lower the tax
+		position := player.Position
+		b.PendingTax = &position
+		b.PendingPercentTax = b.percentTax(player, slot)
+		return fmt.Sprintf("%s landed on %s", player.Name, slot.Name),
+			fmt.Sprintf("Pay %d flat or %d%% of your net worth, %d?", slot.Price, slot.TaxPercent, b.PendingPercentTax), nil
+	default:
+		return b.payFine(player, slot.Price, "taxes", EventTaxPaid)
+	}
+}
+
+// PayTax settles a choice tax the way the player picked.
+func (b *Board) PayTax(player *Player, body GameTaxBody) (string, string, error) {
+	if b.PendingTax == nil {
+		return "", "", fmt.Errorf("no tax to pay")
+	}
+	slot := b.Slots[*b.PendingTax]
+
+	amount := 0
+	switch body.Option {
+	case TaxModeFlat:
+		amount = slot.Price
+	case TaxModePercent:
+		amount = b.PendingPercentTax
+	default:
+		return "", "", fmt.Errorf("choose flat or percent")
+	}
+	b.PendingTax = nil
+	b.PendingPercentTax = 0
+	msg, prompt, err := b.payFine(player, amount, "taxes", EventTaxPaid)
+	b.settle(b.afterRollPhase())
+	return msg, prompt, err
+}
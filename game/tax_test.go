@@ -0,0 +1,30 @@
+package game
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestPercentTaxValuedOnLanding(t *testing.T) {
+	b, a, _ := ledgerBoard()
+	b.startTurn()
+	position := slices.IndexFunc(b.Slots, func(slot Slot) bool { return slot.TaxMode == TaxModeChoice })
+	a.Position = position
+	want := b.NetWorth(a) * b.Slots[position].TaxPercent / 100
+	if _, _, err := b.HandleTaxSlot(a, b.Slots[position]); err != nil {
+		t.Fatal(err)
+	}
+	b.Phase = PhaseAwaitingTax
+
+	// Selling up before paying lowers the net worth, but not the tax
+	if _, _, err := b.SellHouse(a, GamePropertyBody{Property: id(1)}); err != nil {
+		t.Fatal(err)
+	}
+	money := a.Money
+	if _, _, err := b.PayTax(a, GameTaxBody{Option: TaxModePercent}); err != nil {
+		t.Fatal(err)
+	}
+	if paid := money - a.Money; paid != want {
+		t.Errorf("paid %d, want %d", paid, want)
+	}
+}
@@ -0,0 +1,340 @@
+package game
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// TradeStatus is where a trade stands, open until it is answered or lapses.
+type TradeStatus string
+
+const (
+	TradeStatusOpen      TradeStatus = "open"
+	TradeStatusAccepted  TradeStatus = "accepted"
+	TradeStatusRejected  TradeStatus = "rejected"
+	TradeStatusCancelled TradeStatus = "cancelled"
+	TradeStatusCountered TradeStatus = "countered"
+	TradeStatusExpired   TradeStatus = "expired"
+	// TradeStatusInvalidated trades fell through because the assets changed hands elsewhere
+	TradeStatusInvalidated TradeStatus = "invalidated"
+)
+
+// TradeLeg moves assets from one party of a trade to another.
+type TradeLeg struct {
+	From    IdType       `json:"from" validate:"required"`
+	To      IdType       `json:"to" validate:"required"`
+	Details TradeDetails `json:"details"`
+}
+
+// legs lists every transfer in a trade: Give and Take between requester and
+// responder, then any further legs between other parties.
+func (trade *GameTradeBody) legs() []TradeLeg {
+	legs := []TradeLeg{}
+	if trade.Responder != nil {
+		legs = append(legs,
+			TradeLeg{From: trade.Requester, To: trade.Responder, Details: trade.Give},
+			TradeLeg{From: trade.Responder, To: trade.Requester, Details: trade.Take})
+	}
+	return append(legs, trade.Legs...)
+}
+
+// parties lists everyone a trade involves, requester first.
+func (trade *GameTradeBody) parties() []IdType {
+	parties := []IdType{trade.Requester}
+	for _, leg := range trade.legs() {
+		for _, id := range []IdType{leg.From, leg.To} {
+			if id != nil && !containsId(parties, id) {
+				parties = append(parties, id)
+			}
+		}
+	}
+	return parties
+}
+
+// involves reports whether the player is a party to the trade.
+func (trade *GameTradeBody) involves(player *Player) bool {
+	return containsId(trade.parties(), player.Id)
+}
+
+// pendingParties lists the parties who still have to accept the trade.
+func (trade *GameTradeBody) pendingParties() []IdType {
+	pending := []IdType{}
+	for _, id := range trade.parties()[1:] {
+		if !containsId(trade.Accepted, id) {
+			pending = append(pending, id)
+		}
+	}
+	return pending
+}
+
+// containsId reports whether the id is in the list, comparing by value.
+func containsId(ids []IdType, id IdType) bool {
+	for _, i := range ids {
+		if *i == *id {
+			return true
+		}
+	}
+	return false
+}
+
+// notifyParties sends a notice to every party of a trade but the one who acted.
+// It must be called with the board locked.
+func (b *Board) notifyParties(trade *GameTradeBody, actor *Player, format string, args ...interface{}) {
+	for _, id := range trade.parties() {
+		if *id != *actor.Id {
+			b.notify(id, format, args...)
+		}
+	}
+}
+
+// tradeById finds an open trade, returning nil if there is none with that id.
+func (b *Board) tradeById(id IdType) *GameTradeBody {
+	if id == nil {
+		return nil
+	}
+	return b.Trades[*id]
+}
+
+// openTrades lists the open trades in the order they were offered.
+func (b *Board) openTrades() []*GameTradeBody {
+	ids := []int{}
+	for id := range b.Trades {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	trades := []*GameTradeBody{}
+	for _, id := range ids {
+		trades = append(trades, b.Trades[id])
+	}
+	return trades
+}
+
+// TradesInvolving lists copies of the open trades the player offered or was offered.
+func (b *Board) TradesInvolving(player *Player) []GameTradeBody {
+	b.Lock()
+	defer b.Unlock()
+	trades := []GameTradeBody{}
+	for _, trade := range b.openTrades() {
+		if trade.involves(player) {
+			trades = append(trades, *trade)
+		}
+	}
+	return trades
+}
+
+// describeTrade spells out what each party of a trade hands over.
+func (b *Board) describeTrade(trade *GameTradeBody) string {
+	parts := []string{}
+	for _, leg := range trade.legs() {
+		parts = append(parts, fmt.Sprintf("%s gives %s %s",
+			b.playerName(leg.From), b.playerName(leg.To), b.describeTradeDetails(leg.Details)))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// describeTradeDetails lists the money, properties and cards on one side of a trade.
+func (b *Board) describeTradeDetails(details TradeDetails) string {
+	parts := []string{}
+	if details.Money > 0 {
+		parts = append(parts, fmt.Sprintf("%d", details.Money))
+	}
+	for _, property := range details.Property {
+		if slot, err := b.slotAt(property); err == nil {
+			parts = append(parts, slot.Name)
+		}
+	}
+	for _, id := range details.Cards {
+		if card := b.cardById(id); card != nil {
+			parts = append(parts, card.Name)
+		}
+	}
+	for _, right := range details.Rights {
+		parts = append(parts, b.describeRight(right))
+	}
+	if len(parts) == 0 {
+		return "nothing"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// playerName is the name of a player, or a placeholder for one who has left the game.
+func (b *Board) playerName(id IdType) string {
+	if id == nil {
+		return ""
+	}
+	if player := b.GetPlayer(id); player != nil {
+		return player.Name
+	}
+	return "a former player"
+}
+
+// hasCards reports whether the player holds every one of the cards.
+func hasCards(player *Player, cards ...IdType) bool {
+	for _, card := range cards {
+		found := false
+		for _, c := range player.Inventory {
+			if *c == *card {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// checkTradeAssets makes sure every party of a trade still holds what they offer.
+// Money is left to the moment the trade is accepted, as balances change all game long.
+func (b *Board) checkTradeAssets(trade *GameTradeBody) error {
+	for _, leg := range trade.legs() {
+		from := b.GetPlayer(leg.From)
+		to := b.GetPlayer(leg.To)
+		if from == nil || to == nil {
+			return fmt.Errorf("trade participant has left the game")
+		}
+		if *from.Id == *to.Id {
+			return fmt.Errorf("cannot trade with yourself")
+		}
+		for _, property := range leg.Details.Property {
+			slot, err := b.slotAt(property)
+			if err != nil {
+				return err
+			}
+			if slot.Owner != from.Id {
+				return fmt.Errorf("%s does not own %s", from.Name, slot.Name)
+			}
+			if slot.State > 0 {
+				return fmt.Errorf("sell the buildings on %s first", slot.Name)
+			}
+		}
+		if !hasCards(from, leg.Details.Cards...) {
+			return fmt.Errorf("%s does not hold the offered cards", from.Name)
+		}
+		for _, right := range leg.Details.Rights {
+			if err := b.checkRight(from, right); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// tradeTransactions turns the legs of a trade into ledger transactions, with the
+// mortgage interest each receiver owes on mortgaged properties.
+// It must be called with the board locked.
+func (b *Board) tradeTransactions(trade *GameTradeBody) []Transaction {
+	transactions := []Transaction{}
+	for _, leg := range trade.legs() {
+		from, to := b.GetPlayer(leg.From), b.GetPlayer(leg.To)
+		transactions = append(transactions,
+			Transaction{From: from, To: to, Money: leg.Details.Money, Properties: leg.Details.Property, Cards: leg.Details.Cards},
+			b.interestTransaction(to, leg.Details.Property))
+	}
+	return transactions
+}
+
+// resolveTrade closes an open trade with the given outcome and records it in the trade history.
+// It must be called with the board locked.
+func (b *Board) resolveTrade(trade *GameTradeBody, outcome TradeStatus) {
+	delete(b.Trades, *trade.Id)
+	trade.Active = false
+	trade.Accept = outcome == TradeStatusAccepted
+	trade.Status = outcome
+	b.TradeHistory = append(b.TradeHistory, TradeHistoryEntry{
+		TradeId:       trade.Id,
+		RequesterName: b.playerName(trade.Requester),
+		ResponderName: b.playerName(trade.Responder),
+		Give:          trade.Give,
+		Take:          trade.Take,
+		Legs:          trade.Legs,
+		Outcome:       outcome,
+		Timestamp:     time.Now().Format(time.RFC3339),
+	})
+	b.emit(EventTradeResolved, nil, TradeResolvedPayload{TradeId: trade.Id, Outcome: outcome})
+}
+
+// RejectTrade turns down a trade offered to the player. One party saying no is enough to end it.
+// It must be called with the board locked.
+func (b *Board) RejectTrade(player *Player, body GameTradeAcceptBody) (string, string, error) {
+	trade := b.tradeById(body.TradeId)
+	if trade == nil {
+		return "", "", fmt.Errorf("no open trade with that id")
+	}
+	if *trade.Requester == *player.Id || !trade.involves(player) {
+		return "", "", fmt.Errorf("not your trade")
+	}
+	b.resolveTrade(trade, TradeStatusRejected)
+	b.notifyParties(trade, player, "%s rejected trade %d", player.Name, *trade.Id)
+	return fmt.Sprintf("%s rejected trade %d from %s", player.Name, *trade.Id, b.playerName(trade.Requester)), "", nil
+}
+
+// CancelTrade withdraws a trade the player offered.
+// It must be called with the board locked.
+func (b *Board) CancelTrade(player *Player, body GameTradeAcceptBody) (string, string, error) {
+	trade := b.tradeById(body.TradeId)
+	if trade == nil {
+		return "", "", fmt.Errorf("no open trade with that id")
+	}
+	if *trade.Requester != *player.Id {
+		return "", "", fmt.Errorf("not your trade")
+	}
+	b.resolveTrade(trade, TradeStatusCancelled)
+	b.notifyParties(trade, player, "%s withdrew their trade %d", player.Name, *trade.Id)
+	return fmt.Sprintf("%s cancelled trade %d", player.Name, *trade.Id), "", nil
+}
+
+// CounterTrade answers an open trade with a new offer, which replaces it.
+// Without a responder or legs of its own, the counter-offer goes back to the original requester.
+// It must be called with the board locked.
+func (b *Board) CounterTrade(player *Player, body GameTradeBody) (string, string, error) {
+	original := b.tradeById(body.Counters)
+	if original == nil {
+		return "", "", fmt.Errorf("no open trade to counter")
+	}
+	if *original.Requester == *player.Id || !original.involves(player) {
+		return "", "", fmt.Errorf("not your trade")
+	}
+	if body.Responder == nil && len(body.Legs) == 0 {
+		body.Responder = original.Requester
+	}
+	return b.proposeTrade(player, body, original)
+}
+
+// expireTrades closes the trades nobody answered in time.
+// It must be called with the board locked.
+func (b *Board) expireTrades(now time.Time) string {
+	messages := []string{}
+	for _, trade := range b.openTrades() {
+		if now.Before(trade.Expires) {
+			continue
+		}
+		b.resolveTrade(trade, TradeStatusExpired)
+		for _, id := range trade.parties() {
+			b.notify(id, "Trade %d from %s expired", *trade.Id, b.playerName(trade.Requester))
+		}
+		messages = append(messages, fmt.Sprintf("Trade %d expired", *trade.Id))
+	}
+	return strings.Join(messages, "\n")
+}
+
+// invalidateTrades closes the trades whose assets changed hands or whose players left since they were offered.
+// It must be called with the board locked.
+func (b *Board) invalidateTrades() string {
+	messages := []string{}
+	for _, trade := range b.openTrades() {
+		err := b.checkTradeAssets(trade)
+		if err == nil {
+			continue
+		}
+		b.resolveTrade(trade, TradeStatusInvalidated)
+		for _, id := range trade.parties() {
+			b.notify(id, "Trade %d was called off: %s", *trade.Id, err)
+		}
+		messages = append(messages, fmt.Sprintf("Trade %d was called off: %s", *trade.Id, err))
+	}
+	return strings.Join(messages, "\n")
+}
@@ -0,0 +1,36 @@
+package game
+
+import (
+	"testing"
+)
+
+func TestTradeWithMissingIds(t *testing.T) {
+	tests := []struct {
+		name    string
+		details TradeDetails
+	}{
+		{name: "card", details: TradeDetails{Cards: []IdType{nil}}},
+		{name: "property", details: TradeDetails{Property: []IdType{nil}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, a, c := ledgerBoard()
+			body := GameTradeBody{Responder: c.Id, Give: tt.details}
+			events := b.HandleAction(a, "trade", body)
+			last := events[len(events)-1]
+			if last.Type != EventError {
+				t.Fatalf("last event is %s %q, want an error", last.Type, last.Text)
+			}
+			if len(b.Trades) != 0 {
+				t.Errorf("trade was opened")
+			}
+
+			// A leg between other players is checked the same way
+			body = GameTradeBody{Legs: []TradeLeg{{From: a.Id, To: c.Id, Details: tt.details}}}
+			events = b.HandleAction(a, "trade", body)
+			if last := events[len(events)-1]; last.Type != EventError {
+				t.Fatalf("last event is %s %q, want an error", last.Type, last.Text)
+			}
+		})
+	}
+}
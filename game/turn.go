@@ -0,0 +1,174 @@
+package game
+
+import (
+	"fmt"
+	"slices"
+)
+
+// TurnPhase is where the current turn stands, it decides which actions are legal.
+type TurnPhase string
+
+const (
+	// PhasePreRoll starts a turn, the player has not rolled yet
+	PhasePreRoll TurnPhase = "pre_roll"
+	// PhaseJailDecision starts the turn of a jailed player, who must pay, roll or use a card
+	PhaseJailDecision TurnPhase = "jail_decision"
+	// PhaseRolled follows a roll of doubles, the player must roll again
+	PhaseRolled TurnPhase = "rolled"
+	// PhaseAwaitingPurchase waits for the player to buy or decline the unowned slot they landed on
+	PhaseAwaitingPurchase TurnPhase = "awaiting_purchase"
+	// PhaseAwaitingTax waits for the player to choose how to pay a tax
+	PhaseAwaitingTax TurnPhase = "awaiting_tax"
+	// PhaseAuction waits for the running auction to close
+	PhaseAuction TurnPhase = "auction"
+	// PhaseDebt waits for debtors to pay or go bankrupt
+	PhaseDebt TurnPhase = "debt"
+	// PhaseCanEnd has nothing left to do but end the turn
+	PhaseCanEnd TurnPhase = "can_end"
+)
+
+var (
+	// phaseTransitions lists the phases each phase may move on to.
+	// Auctions and debts can interrupt any phase and hand back to any phase.
+	// A new turn may also start from any phase when the current player leaves, see startTurn.
+	phaseTransitions = map[TurnPhase][]TurnPhase{
+		PhasePreRoll:          {PhaseJailDecision, PhaseRolled, PhaseAwaitingPurchase, PhaseAwaitingTax, PhaseAuction, PhaseDebt, PhaseCanEnd},
+		PhaseJailDecision:     {PhasePreRoll, PhaseAwaitingPurchase, PhaseAwaitingTax, PhaseAuction, PhaseDebt, PhaseCanEnd},
+		PhaseRolled:           {PhaseRolled, PhaseAwaitingPurchase, PhaseAwaitingTax, PhaseAuction, PhaseDebt, PhaseCanEnd},
+		PhaseAwaitingPurchase: {PhaseAuction, PhaseDebt, PhaseRolled, PhaseCanEnd},
+		PhaseAwaitingTax:      {PhaseAuction, PhaseDebt, PhaseRolled, PhaseCanEnd},
+		PhaseAuction:          {PhasePreRoll, PhaseJailDecision, PhaseRolled, PhaseAwaitingPurchase, PhaseAwaitingTax, PhaseDebt, PhaseCanEnd},
+		PhaseDebt:             {PhasePreRoll, PhaseJailDecision, PhaseRolled, PhaseAwaitingPurchase, PhaseAwaitingTax, PhaseAuction, PhaseCanEnd},
+		PhaseCanEnd:           {PhaseAuction, PhaseDebt},
+	}
+
+	// turnActions are the actions the current player may take in each phase.
+	turnActions = map[TurnPhase][]string{
+		PhasePreRoll:          {"go", "use_card", "build_house", "unmortgage"},
+		PhaseJailDecision:     {"go", "pay_fine", "use_card", "build_house", "unmortgage"},
+		PhaseRolled:           {"go", "build_house", "unmortgage"},
+		PhaseAwaitingPurchase: {"buy", "decline", "end_turn"},
+		PhaseAwaitingTax:      {"pay_tax"},
+		PhaseDebt:             {"end_turn"},
+		PhaseCanEnd:           {"end_turn", "build_house", "unmortgage"},
+	}
+
+	// openActions are the actions any player may take in each phase.
+	openActions = map[TurnPhase][]string{
+		PhaseAuction: {"bid"},
+		PhaseDebt:    {"pay_debt", "bankrupt"},
+	}
+
+	// anytimeActions may be taken by any player in any phase.
+	anytimeActions = []string{"trade", "accept_trade", "reject_trade", "cancel_trade", "counter_trade", "propose_loan", "accept_loan", "reject_loan", "sell_house", "mortgage", "forfeit_game"}
+)
+
+// setPhase moves the turn to a new phase, refusing transitions the turn cannot make.
+// It does not take the board lock.
+func (b *Board) setPhase(phase TurnPhase) error {
+	if phase == b.Phase {
+		return nil
+	}
+	if !slices.Contains(phaseTransitions[b.Phase], phase) {
+		return fmt.Errorf("turn cannot go from %s to %s", b.Phase, phase)
+	}
+	b.Phase = phase
+	return nil
+}
+
+// startTurn opens a fresh turn for the current player.
+// It does not take the board lock.
+func (b *Board) startTurn() {
+	b.Doubles = 0
+	b.PendingPurchase = nil
+	b.PendingTax = nil
+	b.PendingPercentTax = 0
+	b.resumePhase = ""
+	b.Phase = PhasePreRoll
+	if len(b.Players) == 0 {
+		return
+	}
+	player := b.Players[b.Turn]
+	if player.InJail {
+		b.Phase = PhaseJailDecision
+	}
+	b.emit(EventTurnChanged, player, TurnChangedPayload{TurnNumber: b.TurnNumber, Phase: b.Phase})
+	if player.InJail {
+		b.notify(player.Id, "%s", b.jailPrompt(player))
+	}
+}
+
+// rollAgain reports whether the current player's last roll earned another.
+func (b *Board) rollAgain() bool {
+	return b.Doubles > 0 && b.LastRoll.IsDouble()
+}
+
+// afterRollPhase is where the turn goes once a roll has been made, depending on what the landing left open.
+func (b *Board) afterRollPhase() TurnPhase {
+	switch {
+	case b.PendingPurchase != nil:
+		return PhaseAwaitingPurchase
+	case b.PendingTax != nil:
+		return PhaseAwaitingTax
+	case b.rollAgain():
+		return PhaseRolled
+	default:
+		return PhaseCanEnd
+	}
+}
+
+// settle moves the turn to the given phase, unless an auction or debt has to be dealt with first.
+// The interrupted phase is picked up again once they are resolved.
+// Whatever led here has already changed the board, so a transition the turn
+// refuses does not undo it: the phase stays and the refusal is reported to everyone.
+// It does not take the board lock.
+func (b *Board) settle(next TurnPhase) {
+	phase := next
+	b.resumePhase = ""
+	if b.Auction != nil || len(b.Debts) > 0 {
+		b.resumePhase = next
+		phase = PhaseDebt
+		if b.Auction != nil {
+			phase = PhaseAuction
+		}
+	}
+	if err := b.setPhase(phase); err != nil {
+		b.events = append(b.events, TextEvent(EventError, nil, "turn error: "+err.Error()))
+	}
+}
+
+// refreshPhase re-checks auctions and debts after something outside the
+// normal turn flow may have started or finished one.
+// It does not take the board lock.
+func (b *Board) refreshPhase() {
+	next := b.Phase
+	if next == PhaseAuction || next == PhaseDebt {
+		next = b.resumePhase
+	}
+	b.settle(next)
+}
+
+// AllowedActions lists the actions the player may take right now.
+func (b *Board) AllowedActions(player *Player) []string {
+	b.Lock()
+	defer b.Unlock()
+	return b.allowedActions(player)
+}
+
+// allowedActions is AllowedActions for callers that already hold the board lock.
+func (b *Board) allowedActions(player *Player) []string {
+	if b.GetPlayer(player.Id) == nil {
+		return []string{}
+	}
+	actions := append([]string{}, anytimeActions...)
+	actions = append(actions, openActions[b.Phase]...)
+	if b.Players[b.Turn] == player {
+		actions = append(actions, turnActions[b.Phase]...)
+	}
+	return actions
+}
+
+// isAllowed reports whether the player may take the action in the current phase.
+func (b *Board) isAllowed(player *Player, action string) bool {
+	return slices.Contains(b.allowedActions(player), action)
+}
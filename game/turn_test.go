@@ -0,0 +1,212 @@
+package game
+
+import (
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// turnBoard is a default board with two players in the first turn.
+func turnBoard() (*Board, *Player, *Player) {
+	b := NewBoard()
+	a, c := b.AddPlayer("a"), b.AddPlayer("c")
+	b.startTurn()
+	return b, a, c
+}
+
+func TestPhaseTransitionsCoverEveryPhase(t *testing.T) {
+	phases := []TurnPhase{PhasePreRoll, PhaseJailDecision, PhaseRolled, PhaseAwaitingPurchase,
+		PhaseAwaitingTax, PhaseAuction, PhaseDebt, PhaseCanEnd}
+	for _, phase := range phases {
+		next, ok := phaseTransitions[phase]
+		if !ok {
+			t.Errorf("%s has no transitions", phase)
+		}
+		// Auctions and debts may interrupt any phase and hand back to it
+		for _, interrupt := range []TurnPhase{PhaseAuction, PhaseDebt} {
+			if phase != interrupt && !slices.Contains(next, interrupt) {
+				t.Errorf("%s cannot be interrupted by %s", phase, interrupt)
+			}
+			if phase != interrupt && !slices.Contains(phaseTransitions[interrupt], phase) {
+				t.Errorf("%s cannot resume %s", interrupt, phase)
+			}
+		}
+	}
+}
+
+func TestSetPhase(t *testing.T) {
+	tests := []struct {
+		from, to TurnPhase
+		ok       bool
+	}{
+		{PhasePreRoll, PhaseRolled, true},
+		{PhasePreRoll, PhaseCanEnd, true},
+		{PhasePreRoll, PhasePreRoll, true},
+		{PhaseJailDecision, PhasePreRoll, true},
+		{PhaseRolled, PhaseRolled, true},
+		{PhaseRolled, PhasePreRoll, false},
+		{PhaseAwaitingPurchase, PhaseCanEnd, true},
+		{PhaseAwaitingPurchase, PhasePreRoll, false},
+		{PhaseAwaitingTax, PhaseJailDecision, false},
+		{PhaseCanEnd, PhaseAuction, true},
+		{PhaseCanEnd, PhasePreRoll, false},
+		{PhaseCanEnd, PhaseRolled, false},
+		{PhaseDebt, PhaseAuction, true},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.from)+"->"+string(tt.to), func(t *testing.T) {
+			b, _, _ := turnBoard()
+			b.Phase = tt.from
+			err := b.setPhase(tt.to)
+			if (err == nil) != tt.ok {
+				t.Fatalf("setPhase error = %v, want ok %v", err, tt.ok)
+			}
+			want := tt.to
+			if !tt.ok {
+				want = tt.from
+			}
+			if b.Phase != want {
+				t.Errorf("phase is %s, want %s", b.Phase, want)
+			}
+		})
+	}
+}
+
+func TestSettleAndRefreshPhase(t *testing.T) {
+	tests := []struct {
+		name string
+		// phase and resume are where the turn stands before
+		phase, resume TurnPhase
+		auction, debt bool
+		// settle is the phase passed to settle, empty calls refreshPhase instead
+		settle TurnPhase
+		// want and wantResume are where the turn stands after
+		want, wantResume TurnPhase
+		turnError        bool
+	}{
+		{name: "settle moves on", phase: PhasePreRoll, settle: PhaseCanEnd, want: PhaseCanEnd},
+		{name: "settle into an auction", phase: PhaseAwaitingPurchase, auction: true, settle: PhaseRolled,
+			want: PhaseAuction, wantResume: PhaseRolled},
+		{name: "settle into a debt", phase: PhaseRolled, debt: true, settle: PhaseCanEnd,
+			want: PhaseDebt, wantResume: PhaseCanEnd},
+		{name: "auction before debt", phase: PhasePreRoll, auction: true, debt: true, settle: PhaseCanEnd,
+			want: PhaseAuction, wantResume: PhaseCanEnd},
+		{name: "refused transition", phase: PhaseCanEnd, settle: PhasePreRoll,
+			want: PhaseCanEnd, turnError: true},
+		{name: "refresh resumes after the auction", phase: PhaseAuction, resume: PhaseRolled,
+			want: PhaseRolled},
+		{name: "refresh resumes after the debt", phase: PhaseDebt, resume: PhaseJailDecision,
+			want: PhaseJailDecision},
+		{name: "refresh waits for the debt", phase: PhaseDebt, resume: PhaseCanEnd, debt: true,
+			want: PhaseDebt, wantResume: PhaseCanEnd},
+		{name: "refresh hands the auction over to a debt", phase: PhaseAuction, resume: PhaseCanEnd, debt: true,
+			want: PhaseDebt, wantResume: PhaseCanEnd},
+		{name: "refresh interrupts the phase", phase: PhasePreRoll, debt: true,
+			want: PhaseDebt, wantResume: PhasePreRoll},
+		{name: "refresh keeps the phase", phase: PhaseAwaitingTax, want: PhaseAwaitingTax},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, a, c := turnBoard()
+			b.Phase = tt.phase
+			b.resumePhase = tt.resume
+			if tt.auction {
+				b.Auction = &Auction{Property: 1, Deadline: time.Now().Add(time.Minute)}
+			}
+			if tt.debt {
+				b.Debts = []*Debt{{Debtor: a.Id, Creditor: c.Id, Amount: 100}}
+			}
+			b.drainEvents()
+
+			if tt.settle != "" {
+				b.settle(tt.settle)
+			} else {
+				b.refreshPhase()
+			}
+			if b.Phase != tt.want || b.resumePhase != tt.wantResume {
+				t.Errorf("turn is %s resuming %q, want %s resuming %q", b.Phase, b.resumePhase, tt.want, tt.wantResume)
+			}
+			turnError := slices.ContainsFunc(b.drainEvents(), func(e Event) bool { return e.Type == EventError })
+			if turnError != tt.turnError {
+				t.Errorf("turn error reported = %v, want %v", turnError, tt.turnError)
+			}
+		})
+	}
+}
+
+// TestActionsWhileTicking plays turns while the board ticks on another
+// goroutine, the way a room runs it. It is meant to be run with -race.
+func TestActionsWhileTicking(t *testing.T) {
+	b := NewBoard()
+	a, c := b.AddPlayer("a"), b.AddPlayer("c")
+	b.StartGame()
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	var tickEvents []Event
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				// Closing an auction moves the turn on
+				tickEvents = append(tickEvents, b.Tick(time.Now().Add(time.Hour))...)
+				runtime.Gosched()
+			}
+		}
+	}()
+	events := []Event{}
+	for i := 0; i < 200; i++ {
+		for _, player := range []*Player{a, c} {
+			for _, action := range []string{"go", "decline", "pay_tax", "end_turn"} {
+				events = append(events, b.HandleAction(player, action, GameTaxBody{Option: TaxModeFlat})...)
+				runtime.Gosched()
+			}
+		}
+	}
+	close(done)
+	wg.Wait()
+
+	for _, event := range append(events, tickEvents...) {
+		if event.Type == EventError && strings.HasPrefix(event.Text, "turn error") {
+			t.Errorf("%s", event.Text)
+		}
+	}
+	if _, ok := phaseTransitions[b.Phase]; !ok {
+		t.Errorf("turn ended up in unknown phase %q", b.Phase)
+	}
+	if b.Auction == nil && len(b.Debts) == 0 && (b.Phase == PhaseAuction || b.Phase == PhaseDebt) {
+		t.Errorf("turn is stuck in %s", b.Phase)
+	}
+	if b.TurnNumber < 10 {
+		t.Errorf("only %d turns were played, stuck in %s", b.TurnNumber, b.Phase)
+	}
+	for _, player := range b.Players {
+		if player.Money < 0 {
+			t.Errorf("%s has %d", player.Name, player.Money)
+		}
+	}
+}
+
+func TestDropPlayerInLobby(t *testing.T) {
+	b := NewBoard()
+	a := b.AddPlayer("a")
+	b.AddPlayer("c")
+	b.DrainEvents()
+
+	b.DropPlayer(a)
+	for _, event := range b.DrainEvents() {
+		if event.Type == EventTurnChanged {
+			t.Errorf("turn changed before the game started")
+		}
+	}
+	if b.PlayerCount() != 1 || b.Turn != 0 {
+		t.Errorf("%d players left, turn %d", b.PlayerCount(), b.Turn)
+	}
+}
@@ -0,0 +1,247 @@
+package room
+
+import (
+	"fmt"
+
+	"dhmk/game"
+
+	"github.com/gorilla/websocket"
+)
+
+// RoomState is where a room is in its life: players gather in the lobby until the host starts the game.
+type RoomState string
+
+const (
+	RoomStateLobby    RoomState = "lobby"
+	RoomStateInGame   RoomState = "in_game"
+	RoomStateFinished RoomState = "finished"
+)
+
+// Room actions, sent with CategoryRoom.
+const (
+	// ActionInfo asks for the RoomInfo
+	ActionInfo Action = "info"
+	// ActionReady toggles whether the player is ready to start
+	ActionReady Action = "ready"
+	// ActionStart starts the game, host only
+	ActionStart Action = "start"
+	// ActionKick removes a player from the room, host only
+	ActionKick Action = "kick"
+)
+
+// Events the room sends about its lifecycle.
+const (
+	// EventRoomInfo carries the RoomInfo, after every change in the lobby or when asked for
+	EventRoomInfo  game.EventType = "room_info"
+	EventRoomState game.EventType = "room_state"
+	EventKicked    game.EventType = "player_kicked"
+	EventGameOver  game.EventType = "game_over"
+)
+
+var (
+	// DefaultMinPlayers and DefaultMaxPlayers bound the players a new room starts a game with.
+	DefaultMinPlayers = 2
+	DefaultMaxPlayers = 8
+)
+
+// KickBody names the player to kick.
+type KickBody struct {
+	Player game.IdType `json:"player" validate:"required"`
+}
+
+// RoomInfo describes a room and the players in it.
+type RoomInfo struct {
+	State      RoomState    `json:"state"`
+	Host       game.IdType  `json:"host"`
+	MinPlayers int          `json:"minPlayers"`
+	MaxPlayers int          `json:"maxPlayers"`
+	Players    []LobbyEntry `json:"players"`
+	Spectators int          `json:"spectators"`
+}
+
+// LobbyEntry is a player as the room sees them.
+type LobbyEntry struct {
+	Id        game.IdType `json:"id"`
+	Name      string      `json:"name"`
+	Ready     bool        `json:"ready"`
+	Connected bool        `json:"connected"`
+}
+
+// Info describes the room as it stands.
+func (cr *Room) Info() RoomInfo {
+	cr.Lock()
+	defer cr.Unlock()
+	return cr.info()
+}
+
+// info is Info for callers that already hold the room lock.
+func (cr *Room) info() RoomInfo {
+	info := RoomInfo{
+		State:      cr.State,
+		Host:       cr.hostId(),
+		MinPlayers: cr.MinPlayers,
+		MaxPlayers: cr.MaxPlayers,
+		Players:    []LobbyEntry{},
+		Spectators: len(cr.spectators),
+	}
+	connected := map[int]bool{}
+	for _, player := range cr.players {
+		connected[*player.Id] = true
+	}
+	for _, player := range cr.Board.Snapshot().Players {
+		info.Players = append(info.Players, LobbyEntry{
+			Id:        player.Id,
+			Name:      player.Name,
+			Ready:     cr.ready[*player.Id],
+			Connected: connected[*player.Id],
+		})
+	}
+	return info
+}
+
+// hostId returns the host, handing the role to the first player in the room if the host left.
+// It must be called with the room locked.
+func (cr *Room) hostId() game.IdType {
+	if cr.host != nil && cr.Board.LookupPlayer(cr.host) != nil {
+		return cr.host
+	}
+	cr.host = nil
+	if players := cr.Board.Snapshot().Players; len(players) > 0 {
+		cr.host = players[0].Id
+	}
+	return cr.host
+}
+
+// infoEvent is the room info as an event for everyone.
+// It must be called with the room locked.
+func (cr *Room) infoEvent() game.Event {
+	return game.NewEvent(EventRoomInfo, nil, cr.info())
+}
+
+// canJoin tells whether a new player may take a seat.
+// It must be called with the room locked.
+func (cr *Room) canJoin() error {
+	if cr.State != RoomStateLobby {
+		return fmt.Errorf("the game has already started")
+	}
+	if cr.Board.PlayerCount() >= cr.MaxPlayers {
+		return fmt.Errorf("the room is full")
+	}
+	return nil
+}
+
+// handleRoomMessage runs a room action for a player and returns the events to deliver.
+func (cr *Room) handleRoomMessage(player *game.Player, message Message) []game.Event {
+	cr.Lock()
+	defer cr.Unlock()
+
+	fail := func(format string, args ...interface{}) []game.Event {
+		return []game.Event{game.TextEvent(game.EventError, player.Id, fmt.Sprintf(format, args...))}
+	}
+	isHost := func() bool {
+		host := cr.hostId()
+		return host != nil && *host == *player.Id
+	}
+
+	switch message.Action {
+	case ActionInfo:
+		event := cr.infoEvent()
+		event.Target = player.Id
+		return []game.Event{event}
+	case ActionRules:
+		return []game.Event{{Version: game.EventVersion, Type: EventRules, Payload: cr.Board.Rules, Target: player.Id}}
+	case ActionReady:
+		if cr.State != RoomStateLobby {
+			return fail("the game has already started")
+		}
+		cr.ready[*player.Id] = !cr.ready[*player.Id]
+		return []game.Event{cr.infoEvent()}
+	case ActionStart:
+		if !isHost() {
+			return fail("only the host can start the game")
+		}
+		if cr.State != RoomStateLobby {
+			return fail("the game has already started")
+		}
+		info := cr.info()
+		if count := len(info.Players); count < cr.MinPlayers || count > cr.MaxPlayers {
+			return fail("the game needs %d to %d players, there are %d", cr.MinPlayers, cr.MaxPlayers, count)
+		}
+		for _, entry := range info.Players {
+			if *entry.Id != *player.Id && !entry.Ready {
+				return fail("%s is not ready", entry.Name)
+			}
+		}
+		cr.State = RoomStateInGame
+		events := []game.Event{game.NewEvent(EventRoomState, nil, cr.State)}
+		return append(events, cr.Board.StartGame()...)
+	case ActionKick:
+		if !isHost() {
+			return fail("only the host can kick players")
+		}
+		body, ok := message.Body.(KickBody)
+		if !ok {
+			return fail("invalid kick body")
+		}
+		target := cr.Board.LookupPlayer(body.Player)
+		if target == nil {
+			return fail("player not found")
+		}
+		if *target.Id == *player.Id {
+			return fail("you cannot kick yourself")
+		}
+		return cr.kick(target)
+	}
+	return fail("invalid action")
+}
+
+// kick takes a player out of the room for good, closing their connection and
+// ending their session. Once the game has started they forfeit.
+// It must be called with the room locked.
+func (cr *Room) kick(player *game.Player) []game.Event {
+	event := game.NewEvent(EventKicked, player.Id, nil)
+	event.Text = fmt.Sprintf("%s was kicked", player.Name)
+	events := []game.Event{event}
+
+	for token, s := range cr.sessions {
+		if *s.player.Id == *player.Id {
+			delete(cr.sessions, token)
+		}
+	}
+	for conn, p := range cr.players {
+		if *p.Id == *player.Id {
+			cr.dropConn(conn)
+		}
+	}
+	delete(cr.ready, *player.Id)
+
+	if cr.State == RoomStateLobby {
+		cr.Board.DropPlayer(player)
+		events = append(events, cr.Board.DrainEvents()...)
+		return append(events, cr.infoEvent())
+	}
+	return append(events, cr.Board.HandleAction(player, "forfeit_game", nil)...)
+}
+
+// dropConn closes a connection and forgets it.
+// It must be called with the room locked.
+func (cr *Room) dropConn(conn *websocket.Conn) {
+	conn.Close()
+	delete(cr.Clients, conn)
+	delete(cr.players, conn)
+}
+
+// checkFinished ends the game once a single player is left.
+// It must be called with the room locked.
+func (cr *Room) checkFinished() []game.Event {
+	if cr.State != RoomStateInGame || cr.Board.PlayerCount() > 1 {
+		return nil
+	}
+	cr.State = RoomStateFinished
+	over := game.NewEvent(EventGameOver, nil, nil)
+	if players := cr.Board.Snapshot().Players; len(players) == 1 {
+		over.Player = players[0].Id
+		over.Text = fmt.Sprintf("Game over, %s wins", players[0].Name)
+	}
+	return []game.Event{game.NewEvent(EventRoomState, nil, cr.State), over}
+}
@@ -4,6 +4,10 @@ package room
 import (
 	"fmt"
 	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 
@@ -24,16 +28,47 @@ const (
 	CategoryGame Category = "game"
 	CategoryRoom Category = "room"
 
-	ActionGo          Action = "go"
-	ActionTrade       Action = "trade"
-	ActionAcceptTrade Action = "acceptTrade"
-	ActionMessage     Action = "message"
-	ActionUseCard     Action = "useCard"
-	ActionForfeitGame Action = "forfeit"
-	ActionMortgage    Action = "mortgage"
-	ActionBuyHouse    Action = "house"
-	ActionEndTurn     Action = "end"
-	ActionBuy         Action = "buy"
+	ActionGo           Action = "go"
+	ActionTrade        Action = "trade"
+	ActionAcceptTrade  Action = "acceptTrade"
+	ActionRejectTrade  Action = "rejectTrade"
+	ActionCancelTrade  Action = "cancelTrade"
+	ActionCounterTrade Action = "counterTrade"
+	ActionProposeLoan  Action = "proposeLoan"
+	ActionAcceptLoan   Action = "acceptLoan"
+	ActionRejectLoan   Action = "rejectLoan"
+	ActionMessage      Action = "message"
+	ActionUseCard      Action = "useCard"
+	ActionForfeitGame  Action = "forfeit"
+	ActionMortgage     Action = "mortgage"
+	ActionUnmortgage   Action = "unmortgage"
+	ActionBuyHouse     Action = "house"
+	ActionSellHouse    Action = "sellHouse"
+	ActionEndTurn      Action = "end"
+	ActionBuy          Action = "buy"
+	ActionRules        Action = "rules"
+	ActionDecline      Action = "decline"
+	ActionBid          Action = "bid"
+	ActionPayDebt      Action = "payDebt"
+	ActionBankrupt     Action = "bankrupt"
+	ActionPayFine      Action = "payFine"
+	ActionPayTax       Action = "payTax"
+
+	// ActionTrades asks for the open trades involving the player
+	ActionTrades Action = "trades"
+	// ActionState asks for a snapshot of the whole game, to start from or to
+	// resync with after missing a state diff
+	ActionState Action = "state"
+)
+
+// Events the room sends on top of those of the game.
+const (
+	EventPlayerJoined game.EventType = "player_joined"
+	EventRules        game.EventType = "rules"
+	// EventAllowedActions tells a client which actions it may send right now
+	EventAllowedActions game.EventType = "allowed_actions"
+	// EventTrades answers ActionTrades
+	EventTrades game.EventType = "trades"
 )
 
 // Message represents a message sent between client and server over WebSocket.
@@ -52,11 +87,61 @@ type RoomMessageBody struct {
 type Room struct {
 	Board     *game.Board
 	Clients   map[*websocket.Conn]string
-	Broadcast chan string
+	Broadcast chan []game.Event
 	sync.Mutex
+
+	// players maps each connection to the player it plays as
+	players map[*websocket.Conn]*game.Player
+	// sessions are the players' sessions by token, they outlive connections
+	sessions map[string]*session
+
+	// State is where the room is in its lifecycle, the game only takes actions while it is in game
+	State RoomState
+	// MinPlayers and MaxPlayers bound the players the game can start with
+	MinPlayers int
+	MaxPlayers int
+	// host may start the game and kick players, ready holds who is ready to start by player id
+	host  game.IdType
+	ready map[int]bool
+
+	// spectators are the connections watching the game, SpectatorDelay behind it
+	spectators     map[*websocket.Conn]bool
+	SpectatorDelay time.Duration
+	// delayed are the events waiting for the spectator delay to pass
+	delayed []delayed
 }
 
 var (
+	// BoardsDir is the directory board definition files are looked up in by name.
+	BoardsDir = "boards"
+
+	// gameActions maps client actions to the game actions they trigger.
+	gameActions = map[Action]string{
+		ActionGo:           "go",
+		ActionTrade:        "trade",
+		ActionAcceptTrade:  "accept_trade",
+		ActionRejectTrade:  "reject_trade",
+		ActionCancelTrade:  "cancel_trade",
+		ActionCounterTrade: "counter_trade",
+		ActionProposeLoan:  "propose_loan",
+		ActionAcceptLoan:   "accept_loan",
+		ActionRejectLoan:   "reject_loan",
+		ActionForfeitGame:  "forfeit_game",
+		ActionBuy:          "buy",
+		ActionDecline:      "decline",
+		ActionBid:          "bid",
+		ActionUseCard:      "use_card",
+		ActionPayTax:       "pay_tax",
+		ActionPayFine:      "pay_fine",
+		ActionPayDebt:      "pay_debt",
+		ActionBankrupt:     "bankrupt",
+		ActionEndTurn:      "end_turn",
+		ActionBuyHouse:     "build_house",
+		ActionSellHouse:    "sell_house",
+		ActionMortgage:     "mortgage",
+		ActionUnmortgage:   "unmortgage",
+	}
+
 	rooms   = make(map[string]*Room)
 	roomsMu sync.RWMutex
 
@@ -67,12 +152,22 @@ var (
 	}
 )
 
-// NewRoom creates and returns a new Room instance.
-func NewRoom() *Room {
+// NewRoom creates and returns a new Room instance playing on the given board.
+func NewRoom(board *game.Board) *Room {
 	return &Room{
-		Board:     game.NewBoard(),
+		Board:     board,
 		Clients:   make(map[*websocket.Conn]string),
-		Broadcast: make(chan string),
+		Broadcast: make(chan []game.Event),
+		players:   make(map[*websocket.Conn]*game.Player),
+		sessions:  make(map[string]*session),
+
+		State:      RoomStateLobby,
+		MinPlayers: DefaultMinPlayers,
+		MaxPlayers: DefaultMaxPlayers,
+		ready:      make(map[int]bool),
+
+		spectators:     make(map[*websocket.Conn]bool),
+		SpectatorDelay: DefaultSpectatorDelay,
 	}
 }
 
@@ -82,48 +177,162 @@ func GetOrCreateRoom(key string) *Room {
 	defer roomsMu.Unlock()
 	room, ok := rooms[key]
 	if !ok {
-		room = NewRoom()
+		room = NewRoom(game.NewBoard())
 		rooms[key] = room
 		go room.Run()
 	}
 	return room
 }
 
-// Run listens for broadcast messages and sends them to all connected clients.
+// LoadBoard returns a new board from the named definition file in BoardsDir,
+// or the built-in board when no name is given.
+func LoadBoard(name string) (*game.Board, error) {
+	if name == "" {
+		return game.NewBoard(), nil
+	}
+	if name != filepath.Base(name) || strings.HasPrefix(name, ".") {
+		return nil, fmt.Errorf("invalid board name %q", name)
+	}
+	for _, ext := range []string{".json", ".yaml", ".yml"} {
+		path := filepath.Join(BoardsDir, name+ext)
+		if _, err := os.Stat(path); err == nil {
+			return game.LoadBoard(path)
+		}
+	}
+	return nil, fmt.Errorf("board %q not found", name)
+}
+
+// CreateRoom creates a room under a new key playing on the named board with the named rules preset.
+// Spectators watch the game spectatorDelay behind it, zero shows it to them live.
+func CreateRoom(key string, boardName string, rulesName string, spectatorDelay time.Duration) (*Room, error) {
+	if spectatorDelay < 0 {
+		return nil, fmt.Errorf("invalid spectator delay %s", spectatorDelay)
+	}
+	board, err := LoadBoard(boardName)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := game.RulesPreset(rulesName)
+	if err != nil {
+		return nil, err
+	}
+	board.ApplyRules(rules)
+
+	roomsMu.Lock()
+	defer roomsMu.Unlock()
+	if _, ok := rooms[key]; ok {
+		return nil, fmt.Errorf("room %s already exists", key)
+	}
+	room := NewRoom(board)
+	room.SpectatorDelay = spectatorDelay
+	rooms[key] = room
+	go room.Run()
+	return room, nil
+}
+
+// Run listens for broadcast events and sends them to the connected clients.
+// It also ticks the board once a second so timed state such as auctions can resolve,
+// and checks on disconnected players.
 func (cr *Room) Run() {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
 	for {
-		msg := <-cr.Broadcast
-		cr.Lock()
-		for client := range cr.Clients {
-			err := client.WriteMessage(websocket.TextMessage, []byte(msg))
-			if err != nil {
-				client.Close()
-				delete(cr.Clients, client)
+		select {
+		case events := <-cr.Broadcast:
+			cr.writeAll(events)
+		case now := <-ticker.C:
+			events := append(cr.Board.Tick(now), cr.checkSessions(now)...)
+			if len(events) > 0 {
+				cr.writeAll(events)
 			}
 		}
-		cr.Unlock()
 	}
 }
 
-// MessageAll sends a message to all connected clients.
-func (cr *Room) MessageAll(message string) {
-	cr.Broadcast <- message
+// writeAll writes events to the connected clients they are meant for, dropping clients that fail.
+// As the game may have moved on, it may be over, and each client is then told what it may do next.
+func (cr *Room) writeAll(events []game.Event) {
+	cr.Lock()
+	defer cr.Unlock()
+	events = append(events, cr.checkFinished()...)
+	for client := range cr.Clients {
+		err := cr.writeEvents(client, events)
+		if err == nil {
+			err = cr.writeAllowedActions(client)
+		}
+		if err != nil {
+			cr.dropConn(client)
+		}
+	}
+	cr.toSpectators(nil, events)
 }
 
-// MessagePlayer sends a message to a specific player by name.
-func (cr *Room) MessagePlayer(player string, message string) {
-	for conn, name := range cr.Clients {
-		if name == player {
-			conn.WriteMessage(websocket.TextMessage, []byte(message))
-			break
+// writeEvents sends a client the events meant for everyone and for its player.
+// It must be called with the room locked.
+func (cr *Room) writeEvents(client *websocket.Conn, events []game.Event) error {
+	player := cr.players[client]
+	for _, event := range events {
+		if event.Target != nil && (player == nil || *event.Target != *player.Id) {
+			continue
+		}
+		if err := writeEvent(client, event); err != nil {
+			return err
 		}
 	}
+	return nil
+}
+
+// writeEvent sends a single event envelope to a client.
+func writeEvent(client *websocket.Conn, event game.Event) error {
+	msg, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return client.WriteMessage(websocket.TextMessage, msg)
+}
+
+// writeAllowedActions sends a client the actions its player may take in the current turn phase.
+// Outside the game, in the lobby or once it is over, there are none.
+// It must be called with the room locked.
+func (cr *Room) writeAllowedActions(client *websocket.Conn) error {
+	player, ok := cr.players[client]
+	if !ok {
+		return nil
+	}
+	actions := []Action{}
+	allowed := []string{}
+	if cr.State == RoomStateInGame {
+		allowed = cr.Board.AllowedActions(player)
+	}
+	for _, gameAllowed := range allowed {
+		for action, gameAction := range gameActions {
+			if gameAction == gameAllowed {
+				actions = append(actions, action)
+			}
+		}
+	}
+	slices.Sort(actions)
+	return writeEvent(client, game.NewEvent(EventAllowedActions, player.Id, actions))
+}
+
+// MessageAll sends events to the connected clients they are meant for.
+func (cr *Room) MessageAll(events ...game.Event) {
+	cr.Broadcast <- events
+}
+
+// MessagePlayer sends an event to a single connection, whoever it is meant for.
+func (cr *Room) MessagePlayer(conn *websocket.Conn, event game.Event) {
+	cr.Lock()
+	defer cr.Unlock()
+	if err := writeEvent(conn, event); err != nil {
+		fmt.Println("Write error:", err)
+	}
 }
 
 // getBodyStr marshals the body to a JSON string for further processing.
 func getBodyStr(body interface{}) (string, error) {
 	if body == nil {
-		return "", fmt.Errorf("body is required for trade action")
+		return "", fmt.Errorf("body is required")
 	}
 	bodyBytes, err := json.Marshal(body)
 	if err != nil {
@@ -155,7 +364,7 @@ func convertMessage(msg []byte, message *Message) error {
 
 	// for cases tht require body
 	switch message.Action {
-	case ActionTrade:
+	case ActionTrade, ActionCounterTrade:
 		bodyStr, err := getBodyStr(message.Body)
 		if err != nil {
 			return fmt.Errorf("failed to get body string: %w", err)
@@ -168,7 +377,7 @@ func convertMessage(msg []byte, message *Message) error {
 
 		// Replace the Body with the parsed GameTradeBody
 		message.Body = gameTradeBody
-	case ActionAcceptTrade:
+	case ActionAcceptTrade, ActionRejectTrade, ActionCancelTrade:
 		bodyStr, err := getBodyStr(message.Body)
 		if err != nil {
 			return fmt.Errorf("failed to get body string: %w", err)
@@ -181,12 +390,91 @@ func convertMessage(msg []byte, message *Message) error {
 		}
 		// Replace the Body with the parsed GameTradeBody
 		message.Body = gameTradeAcceptBody
+	case ActionBuyHouse, ActionSellHouse, ActionMortgage, ActionUnmortgage:
+		bodyStr, err := getBodyStr(message.Body)
+		if err != nil {
+			return fmt.Errorf("failed to get body string: %w", err)
+		}
+
+		var gamePropertyBody game.GamePropertyBody
+		if err := json.Unmarshal([]byte(bodyStr), &gamePropertyBody); err != nil {
+			return fmt.Errorf("failed to unmarshal body into GamePropertyBody: %w", err)
+		}
+		message.Body = gamePropertyBody
+	case ActionBid:
+		bodyStr, err := getBodyStr(message.Body)
+		if err != nil {
+			return fmt.Errorf("failed to get body string: %w", err)
+		}
+
+		var gameBidBody game.GameBidBody
+		if err := json.Unmarshal([]byte(bodyStr), &gameBidBody); err != nil {
+			return fmt.Errorf("failed to unmarshal body into GameBidBody: %w", err)
+		}
+		message.Body = gameBidBody
+	case ActionUseCard:
+		bodyStr, err := getBodyStr(message.Body)
+		if err != nil {
+			return fmt.Errorf("failed to get body string: %w", err)
+		}
+
+		var gameUseCardBody game.GameUseCardBody
+		if err := json.Unmarshal([]byte(bodyStr), &gameUseCardBody); err != nil {
+			return fmt.Errorf("failed to unmarshal body into GameUseCardBody: %w", err)
+		}
+		message.Body = gameUseCardBody
+	case ActionProposeLoan:
+		bodyStr, err := getBodyStr(message.Body)
+		if err != nil {
+			return fmt.Errorf("failed to get body string: %w", err)
+		}
+
+		var gameLoanBody game.GameLoanBody
+		if err := json.Unmarshal([]byte(bodyStr), &gameLoanBody); err != nil {
+			return fmt.Errorf("failed to unmarshal body into GameLoanBody: %w", err)
+		}
+		message.Body = gameLoanBody
+	case ActionAcceptLoan, ActionRejectLoan:
+		bodyStr, err := getBodyStr(message.Body)
+		if err != nil {
+			return fmt.Errorf("failed to get body string: %w", err)
+		}
+
+		var gameLoanIdBody game.GameLoanIdBody
+		if err := json.Unmarshal([]byte(bodyStr), &gameLoanIdBody); err != nil {
+			return fmt.Errorf("failed to unmarshal body into GameLoanIdBody: %w", err)
+		}
+		message.Body = gameLoanIdBody
+	case ActionKick:
+		bodyStr, err := getBodyStr(message.Body)
+		if err != nil {
+			return fmt.Errorf("failed to get body string: %w", err)
+		}
+
+		var kickBody KickBody
+		if err := json.Unmarshal([]byte(bodyStr), &kickBody); err != nil {
+			return fmt.Errorf("failed to unmarshal body into KickBody: %w", err)
+		}
+		message.Body = kickBody
+	case ActionPayTax:
+		bodyStr, err := getBodyStr(message.Body)
+		if err != nil {
+			return fmt.Errorf("failed to get body string: %w", err)
+		}
+
+		var gameTaxBody game.GameTaxBody
+		if err := json.Unmarshal([]byte(bodyStr), &gameTaxBody); err != nil {
+			return fmt.Errorf("failed to unmarshal body into GameTaxBody: %w", err)
+		}
+		message.Body = gameTaxBody
 	}
 	// Assign the processed message to the output parameter
 	return nil
 }
 
 // HandleWebSocket upgrades the HTTP connection to a WebSocket, registers the player, and processes incoming messages.
+// A client that sends the token of its session plays on as the same player, anyone else joins as a new player.
+// Clients asking to spectate, and those who can no longer join, watch the game instead.
 func (cr *Room) HandleWebSocket(c *gin.Context) {
 	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
@@ -194,26 +482,60 @@ func (cr *Room) HandleWebSocket(c *gin.Context) {
 		return
 	}
 
+	if c.Query("spectate") == "1" {
+		cr.spectate(conn, "You are spectating")
+		return
+	}
+
+	cr.Lock()
+	var joined game.Event
+	s := cr.resume(c.Query("token"), conn)
+	if s != nil {
+		joined = game.NewEvent(EventPlayerReturned, s.player.Id, nil)
+		joined.Text = fmt.Sprintf("%s is back", s.player.Name)
+	} else {
+		if err := cr.canJoin(); err != nil {
+			cr.Unlock()
+			cr.spectate(conn, fmt.Sprintf("You are spectating, %s", err))
+			return
+		}
+		// Get player name from query
+		name := c.Query("name")
+		if name == "" {
+			// Assign player name as Player-N instead
+			name = fmt.Sprintf("Player-%d", len(cr.Clients)+1)
+		}
+		s = &session{token: newToken(), player: cr.Board.AddPlayer(name), conn: conn}
+		joined = game.NewEvent(EventPlayerJoined, s.player.Id, map[string]string{"name": name})
+		joined.Text = fmt.Sprintf("%s joined the game!", name)
+	}
+	player := s.player
+	cr.sessions[s.token] = s
+	cr.Clients[conn] = player.Name
+	cr.players[conn] = player
+	info := cr.infoEvent()
+	cr.Unlock()
+
 	defer func() {
 		cr.Lock()
 		delete(cr.Clients, conn)
+		delete(cr.players, conn)
+		left := cr.disconnect(s, conn)
 		cr.Unlock()
 		conn.Close()
+		if left && cr.Board.LookupPlayer(player.Id) != nil {
+			event := game.NewEvent(EventPlayerLeft, player.Id, nil)
+			event.Text = fmt.Sprintf("%s disconnected, waiting %s for them to come back", player.Name, cr.Board.Rules.ReconnectGrace)
+			cr.MessageAll(event)
+		}
 	}()
 
-	// Get player name from query
-	name := c.Query("name")
-	if name == "" {
-		// Assign player name as Player-N instead
-		name = fmt.Sprintf("Player-%d", len(cr.Clients)+1)
-	}
-
-	player := cr.Board.AddPlayer(name)
-	cr.Lock()
-	cr.Clients[conn] = name
-	cr.Unlock()
+	cr.MessageAll(append(append([]game.Event{joined}, cr.Board.DrainEvents()...), info)...)
 
-	cr.Broadcast <- fmt.Sprintf("%s joined the game!", name)
+	// Let the player know how to come back, which house rules are in effect and where the game stands
+	cr.MessagePlayer(conn, game.NewEvent(EventSession, player.Id, SessionBody{Token: s.token, Player: player.Id}))
+	cr.MessagePlayer(conn, game.NewEvent(EventRules, nil, cr.Board.Rules))
+	cr.MessagePlayer(conn, game.NewEvent(game.EventState, nil, cr.Board.Snapshot()))
 
 	for {
 		_, msg, err := conn.ReadMessage()
@@ -225,54 +547,39 @@ func (cr *Room) HandleWebSocket(c *gin.Context) {
 		var message Message
 		err = convertMessage(msg, &message)
 		if err != nil {
-			cr.MessagePlayer(name, "invalid message format")
+			cr.MessagePlayer(conn, game.TextEvent(game.EventError, player.Id, "invalid message format"))
 			fmt.Println("Error:", err)
 		}
 
 		switch message.Category {
 		case CategoryGame:
 			// Handle game messages
-			// if cr.Board.CurrentPlayer().Name == name {
-			// Map Action type to string for game.HandleAction
-			actionString := ""
-			var body interface{}
+			// Queries only answer the asking player and change nothing
 			switch message.Action {
-			case ActionTrade:
-				actionString = "trade"
-				body = message.Body
-			case ActionAcceptTrade:
-				actionString = "accept_trade"
-				body = message.Body
-			case ActionForfeitGame:
-				actionString = "forfeit_game"
-				body = nil
-			case ActionGo:
-				actionString = "go"
-				body = nil
-			case ActionBuy:
-				actionString = "buy"
-				body = nil
-			case ActionEndTurn:
-				actionString = "end_turn"
-				body = nil
-			// Add more actions as needed
-			default:
-				cr.MessagePlayer(name, "invalid action")
+			case ActionTrades:
+				cr.MessagePlayer(conn, game.NewEvent(EventTrades, player.Id, cr.Board.TradesInvolving(player)))
+				continue
+			case ActionState:
+				cr.MessagePlayer(conn, game.NewEvent(game.EventState, nil, cr.Board.Snapshot()))
 				continue
 			}
-
-			broadcastMessage, promptMessage, err := cr.Board.HandleAction(player, actionString, body)
-			if err != nil {
-				cr.MessagePlayer(name, err.Error())
-			}
-			if broadcastMessage != "" {
-				cr.MessageAll(broadcastMessage)
+			actionString, ok := gameActions[message.Action]
+			if !ok {
+				cr.MessagePlayer(conn, game.TextEvent(game.EventError, player.Id, "invalid action"))
+				continue
 			}
-			if promptMessage != "" {
-				cr.MessagePlayer(name, promptMessage)
+			cr.Lock()
+			state := cr.State
+			cr.Unlock()
+			if state != RoomStateInGame {
+				cr.MessagePlayer(conn, game.TextEvent(game.EventError, player.Id, fmt.Sprintf("the room is in the %s, not in game", state)))
+				continue
 			}
+
+			cr.MessageAll(cr.Board.HandleAction(player, actionString, message.Body)...)
 		case CategoryRoom:
-		// Handle room messages
+			// Handle room messages
+			cr.MessageAll(cr.handleRoomMessage(player, message)...)
 		default:
 			// Handle default messages
 			fmt.Println(fmt.Errorf("Category %s Not defined", message.Category))
@@ -280,8 +587,10 @@ func (cr *Room) HandleWebSocket(c *gin.Context) {
 	}
 }
 
-// CreateRandomRoom generates a random room key, creates the room, and returns the key.
-func CreateRandomRoom() string {
+// CreateRandomRoom generates a random room key, creates the room on the named board with the named rules
+// and spectator delay, and returns the key.
+// Empty names use the built-in board and the classic rules.
+func CreateRandomRoom(boardName string, rulesName string, spectatorDelay time.Duration) (string, error) {
 	const letters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
 	keyLen := 6
 	roomKeyBytes := make([]byte, keyLen)
@@ -289,8 +598,10 @@ func CreateRandomRoom() string {
 		roomKeyBytes[i] = letters[randInt(len(letters))]
 	}
 	roomKey := string(roomKeyBytes)
-	GetOrCreateRoom(roomKey)
-	return roomKey
+	if _, err := CreateRoom(roomKey, boardName, rulesName, spectatorDelay); err != nil {
+		return "", err
+	}
+	return roomKey, nil
 }
 
 // randInt returns a random int in [0, n)
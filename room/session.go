@@ -0,0 +1,144 @@
+package room
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"dhmk/game"
+
+	"github.com/gorilla/websocket"
+)
+
+// Events the room sends about the players' connections.
+const (
+	// EventSession hands a client the token it reconnects with
+	EventSession         game.EventType = "session"
+	EventPlayerLeft      game.EventType = "player_disconnected"
+	EventPlayerReturned  game.EventType = "player_reconnected"
+	EventPlayerAbandoned game.EventType = "player_abandoned"
+)
+
+// session ties a player to whoever plays them, across reconnections.
+type session struct {
+	token  string
+	player *game.Player
+	// conn is the player's current connection, nil while they are disconnected
+	conn *websocket.Conn
+	// left is when the player disconnected
+	left time.Time
+	// bot is set once the game plays for the player, until they come back
+	bot bool
+}
+
+// SessionBody is the body of EventSession.
+type SessionBody struct {
+	Token  string      `json:"token"`
+	Player game.IdType `json:"player"`
+}
+
+// newToken returns a random session token.
+func newToken() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("session token: %v", err))
+	}
+	return hex.EncodeToString(b)
+}
+
+// resume hands a connection the player of an existing session, if the token
+// names one whose player is still in the game. A connection the player still
+// had open is closed, the newest one wins.
+// It must be called with the room locked.
+func (cr *Room) resume(token string, conn *websocket.Conn) *session {
+	s, ok := cr.sessions[token]
+	if !ok || cr.Board.LookupPlayer(s.player.Id) == nil {
+		return nil
+	}
+	if s.conn != nil {
+		cr.dropConn(s.conn)
+	}
+	s.conn = conn
+	s.left = time.Time{}
+	s.bot = false
+	return s
+}
+
+// disconnect marks a session's player as gone, starting their grace period.
+// It must be called with the room locked.
+func (cr *Room) disconnect(s *session, conn *websocket.Conn) bool {
+	if s.conn != conn {
+		// The player already came back on another connection
+		return false
+	}
+	s.conn = nil
+	s.left = time.Now()
+	return true
+}
+
+// checkSessions applies the abandon rule to players gone longer than the grace
+// period, and lets the bot take one action for each player it plays.
+// It returns the events to deliver.
+func (cr *Room) checkSessions(now time.Time) []game.Event {
+	rules := cr.Board.Rules
+	abandoned := []*session{}
+	bots := []*session{}
+	cr.Lock()
+	for token, s := range cr.sessions {
+		if cr.Board.LookupPlayer(s.player.Id) == nil {
+			delete(cr.sessions, token)
+			continue
+		}
+		if s.conn != nil {
+			continue
+		}
+		if s.bot {
+			bots = append(bots, s)
+		} else if now.Sub(s.left) >= rules.ReconnectGrace {
+			abandoned = append(abandoned, s)
+		}
+	}
+	cr.Unlock()
+
+	events := []game.Event{}
+	for _, s := range abandoned {
+		cr.Lock()
+		returned := s.conn != nil
+		state := cr.State
+		if !returned && state != RoomStateLobby && rules.Abandoned == game.AbandonBot {
+			s.bot = true
+		}
+		cr.Unlock()
+		if returned {
+			continue
+		}
+		event := game.NewEvent(EventPlayerAbandoned, s.player.Id, nil)
+		if state == RoomStateLobby {
+			// Nothing has been played yet, the seat is simply freed
+			cr.Board.DropPlayer(s.player)
+			event.Text = fmt.Sprintf("%s did not come back and left the room", s.player.Name)
+			events = append(events, event)
+			events = append(events, cr.Board.DrainEvents()...)
+			cr.Lock()
+			delete(cr.ready, *s.player.Id)
+			events = append(events, cr.infoEvent())
+			cr.Unlock()
+			continue
+		}
+		if rules.Abandoned == game.AbandonBot {
+			event.Text = fmt.Sprintf("%s did not come back, a bot plays for them", s.player.Name)
+			events = append(events, event)
+			continue
+		}
+		event.Text = fmt.Sprintf("%s did not come back and forfeits", s.player.Name)
+		events = append(events, event)
+		events = append(events, cr.Board.HandleAction(s.player, "forfeit_game", nil)...)
+	}
+	for _, s := range bots {
+		if action, body, ok := cr.Board.BotAction(s.player); ok {
+			events = append(events, cr.Board.HandleAction(s.player, action, body)...)
+		}
+	}
+	return events
+}
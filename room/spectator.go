@@ -0,0 +1,125 @@
+package room
+
+import (
+	"fmt"
+	"time"
+
+	"dhmk/game"
+
+	"github.com/gorilla/websocket"
+)
+
+// EventSpectating tells a connection it watches the game instead of playing, and why.
+const EventSpectating game.EventType = "spectating"
+
+// DefaultSpectatorDelay is how far behind the game NewRoom shows it to spectators,
+// CreateRoom takes a delay of its own.
+var DefaultSpectatorDelay time.Duration
+
+// delayed are events held back from spectators until at.
+type delayed struct {
+	at time.Time
+	// conns are the spectators who were watching when the events happened
+	conns  []*websocket.Conn
+	events []game.Event
+}
+
+// spectate serves a connection that watches the game. Spectators see what
+// everyone sees, SpectatorDelay late, and may only ask for the state and the room info.
+func (cr *Room) spectate(conn *websocket.Conn, reason string) {
+	cr.Lock()
+	cr.spectators[conn] = true
+	cr.toSpectators(conn, []game.Event{
+		game.TextEvent(EventSpectating, nil, reason),
+		game.NewEvent(EventRules, nil, cr.Board.Rules),
+		game.NewEvent(game.EventState, nil, cr.Board.Snapshot()),
+	})
+	info := cr.infoEvent()
+	cr.Unlock()
+	cr.MessageAll(info)
+
+	defer func() {
+		cr.Lock()
+		cr.dropSpectator(conn)
+		info := cr.infoEvent()
+		cr.Unlock()
+		cr.MessageAll(info)
+	}()
+
+	for {
+		_, msg, err := conn.ReadMessage()
+		if err != nil {
+			fmt.Println("Read error:", err)
+			break
+		}
+
+		var message Message
+		if err := convertMessage(msg, &message); err != nil {
+			fmt.Println("Error:", err)
+		}
+
+		var event game.Event
+		switch {
+		case message.Category == CategoryGame && message.Action == ActionState:
+			event = game.NewEvent(game.EventState, nil, cr.Board.Snapshot())
+		case message.Category == CategoryRoom && message.Action == ActionInfo:
+			event = game.NewEvent(EventRoomInfo, nil, cr.Info())
+		default:
+			event = game.TextEvent(game.EventError, nil, "spectators cannot act")
+		}
+		cr.Lock()
+		cr.toSpectators(conn, []game.Event{event})
+		cr.Unlock()
+	}
+}
+
+// toSpectators sends events to one spectator, or to all of them for a nil conn.
+// With a spectator delay they are queued and sent once it has passed.
+// It must be called with the room locked.
+func (cr *Room) toSpectators(conn *websocket.Conn, events []game.Event) {
+	conns := []*websocket.Conn{conn}
+	if conn == nil {
+		conns = []*websocket.Conn{}
+		for spectator := range cr.spectators {
+			conns = append(conns, spectator)
+		}
+	}
+	if cr.SpectatorDelay <= 0 {
+		cr.writeSpectators(conns, events)
+		return
+	}
+	cr.delayed = append(cr.delayed, delayed{at: time.Now().Add(cr.SpectatorDelay), conns: conns, events: events})
+	time.AfterFunc(cr.SpectatorDelay, cr.flushSpectators)
+}
+
+// flushSpectators sends the held back events whose delay has passed, in the order they came.
+func (cr *Room) flushSpectators() {
+	cr.Lock()
+	defer cr.Unlock()
+	now := time.Now()
+	for len(cr.delayed) > 0 && !cr.delayed[0].at.After(now) {
+		cr.writeSpectators(cr.delayed[0].conns, cr.delayed[0].events)
+		cr.delayed = cr.delayed[1:]
+	}
+}
+
+// writeSpectators writes events to the spectators still watching, dropping those that fail.
+// Events meant for a single player are never shown.
+// It must be called with the room locked.
+func (cr *Room) writeSpectators(conns []*websocket.Conn, events []game.Event) {
+	for _, spectator := range conns {
+		if !cr.spectators[spectator] {
+			continue
+		}
+		if err := cr.writeEvents(spectator, events); err != nil {
+			cr.dropSpectator(spectator)
+		}
+	}
+}
+
+// dropSpectator closes a spectator's connection and forgets it.
+// It must be called with the room locked.
+func (cr *Room) dropSpectator(conn *websocket.Conn) {
+	conn.Close()
+	delete(cr.spectators, conn)
+}